@@ -0,0 +1,111 @@
+package config
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// commandSource runs the shell command in Options["cmd"], e.g. `docker logs -f svc` or
+// `ssh host tail -F /var/log/app.log`, and reads its output as rows.
+//
+// Standard error is read too if Options["stderr"] is true, in which case each row's Origin tells
+// which of "stdout" and "stderr" it came from. The command is started by Follow and killed when
+// following stops, so restarting the follower restarts the command.
+type commandSource struct {
+	cmd    string
+	stderr bool
+}
+
+// commandWaitDelay is how long to wait for the command's output to be closed after it has exited.
+var commandWaitDelay = time.Second
+
+func fromCommand(logView LogView) *commandSource {
+	cmd, exists := logView.Options["cmd"]
+	if !exists {
+		panic("cmd not found")
+	}
+	stderr, _ := strconv.ParseBool(logView.Options["stderr"])
+	return &commandSource{cmd: cmd, stderr: stderr}
+}
+
+func (cs *commandSource) Rows() ([]Row, error) {
+	fmt.Fprintf(os.Stderr, "Running '%s'", cs.cmd)
+	return []Row{}, nil
+}
+
+func (cs *commandSource) Follow(ctx context.Context, updates chan<- Update) error {
+	cmd := exec.CommandContext(ctx, "sh", "-c", cs.cmd)
+	killProcessGroup(cmd)
+	// Processes started in the background may keep the output open after the command has exited;
+	// stop reading it commandWaitDelay after that
+	cmd.WaitDelay = commandWaitDelay
+	outputs := make(map[string]io.Reader)
+	writers := make([]*io.PipeWriter, 0, 2)
+	stdout, stdoutWriter := io.Pipe()
+	cmd.Stdout = stdoutWriter
+	outputs["stdout"], writers = stdout, append(writers, stdoutWriter)
+	if cs.stderr {
+		stderr, stderrWriter := io.Pipe()
+		cmd.Stderr = stderrWriter
+		outputs["stderr"], writers = stderr, append(writers, stderrWriter)
+	}
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	// Wait returns once the command has exited and its output has been read to the end, or
+	// commandWaitDelay after it exited. The scanners are done when the pipes are closed after that.
+	exited := make(chan error, 1)
+	go func() {
+		err := cmd.Wait()
+		for _, writer := range writers {
+			writer.Close()
+		}
+		exited <- err
+	}()
+	if !send(ctx, updates, Update{Status: fmt.Sprintf("Running '%s'", cs.cmd)}) {
+		return nil
+	}
+
+	lines := make(chan Row, maxBatch)
+	var wg sync.WaitGroup
+	for name, output := range outputs {
+		origin := name
+		if !cs.stderr {
+			origin = ""
+		}
+		wg.Add(1)
+		go func(output io.Reader) {
+			defer wg.Done()
+			scanLines(output, origin, lines)
+		}(output)
+	}
+	go func() {
+		wg.Wait()
+		close(lines)
+	}()
+
+	forwardLines(ctx, lines, updates)
+	if ctx.Err() != nil {
+		// The command is killed along with ctx; drain what the scanners have left while reaping it.
+		go func() {
+			for range lines {
+			}
+		}()
+		<-exited
+		return nil
+	}
+
+	status := fmt.Sprintf("'%s' exited", cs.cmd)
+	if err := <-exited; err != nil && !errors.Is(err, exec.ErrWaitDelay) {
+		status = fmt.Sprintf("'%s' failed: %s", cs.cmd, err)
+	}
+	send(ctx, updates, Update{Status: status})
+	return nil
+}
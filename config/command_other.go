@@ -0,0 +1,9 @@
+//go:build !unix
+
+package config
+
+import "os/exec"
+
+// killProcessGroup leaves cmd to be cancelled by killing just the process itself, as process
+// groups are a unix thing.
+func killProcessGroup(cmd *exec.Cmd) {}
@@ -0,0 +1,100 @@
+package config
+
+import (
+	"context"
+	"os/exec"
+	"strings"
+	"testing"
+	"time"
+
+	. "github.com/torarvid/gloglog/testutil"
+)
+
+func TestCommandSource(t *testing.T) {
+	source := fromCommand(LogView{Options: map[string]string{
+		"cmd":    "echo out; echo err >&2; exit 3",
+		"stderr": "true",
+	}})
+
+	updates := make(chan Update)
+	go func() {
+		defer close(updates)
+		source.Follow(context.Background(), updates)
+	}()
+
+	rows := make(map[string]string)
+	var status string
+	for update := range updates {
+		for _, row := range update.Rows {
+			rows[row.Origin] = row.Line
+		}
+		if update.Status != "" {
+			status = update.Status
+		}
+	}
+	AssertEq(t, "out", rows["stdout"])
+	AssertEq(t, "err", rows["stderr"])
+	if !strings.Contains(status, "exit status 3") {
+		t.Errorf("Expected status to tell the exit status, got '%s'", status)
+	}
+}
+
+func TestCommandSourceKillsPipeline(t *testing.T) {
+	source := fromCommand(LogView{Options: map[string]string{"cmd": "sleep 4243 | cat"}})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	updates := make(chan Update)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		source.Follow(ctx, updates)
+	}()
+	<-updates
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Follow did not return after being cancelled")
+	}
+
+	// Killed processes that were reparented may take a moment to be reaped
+	for i := 0; ; i++ {
+		out, err := exec.Command("pgrep", "-f", "sleep 4243").Output()
+		if err != nil {
+			break
+		}
+		if i == 50 {
+			t.Fatalf("Expected the pipeline to be killed, but found %s", out)
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+}
+
+func TestCommandSourceBackgroundChild(t *testing.T) {
+	commandWaitDelay = 100 * time.Millisecond
+	source := fromCommand(LogView{Options: map[string]string{"cmd": "echo hi; sleep 5 &"}})
+
+	updates := make(chan Update)
+	go func() {
+		defer close(updates)
+		source.Follow(context.Background(), updates)
+	}()
+
+	lines := make([]string, 0)
+	timeout := time.After(3 * time.Second)
+	for {
+		select {
+		case update := <-updates:
+			for _, row := range update.Rows {
+				lines = append(lines, row.Line)
+			}
+			if strings.Contains(update.Status, "exited") {
+				AssertEq(t, 1, len(lines))
+				AssertEq(t, "hi", lines[0])
+				return
+			}
+		case <-timeout:
+			t.Fatal("Expected the command to exit although its background child still runs")
+		}
+	}
+}
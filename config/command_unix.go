@@ -0,0 +1,17 @@
+//go:build unix
+
+package config
+
+import (
+	"os/exec"
+	"syscall"
+)
+
+// killProcessGroup makes cmd start its own process group, and be cancelled by killing the whole
+// group, so that the commands in a pipeline like `sleep 10 | cat` are killed along with the shell.
+func killProcessGroup(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	cmd.Cancel = func() error {
+		return syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+	}
+}
@@ -0,0 +1,46 @@
+package config
+
+import (
+	"bufio"
+	"bytes"
+	"compress/bzip2"
+	"compress/gzip"
+	"io"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+var (
+	gzipMagic  = []byte{0x1f, 0x8b}
+	bzip2Magic = []byte("BZh")
+	zstdMagic  = []byte{0x28, 0xb5, 0x2f, 0xfd}
+)
+
+// decompress looks at the first bytes of reader, and if they are the magic bytes of a gzip, bzip2
+// or zstd stream, returns a reader that decompresses the stream on the fly. Otherwise the data is
+// returned as is. The second return value tells whether the data was compressed. The returned
+// reader must be closed, which doesn't close the underlying reader.
+//
+// Concatenated gzip members (as produced by e.g. `cat a.gz b.gz`) are read as one stream.
+func decompress(reader io.Reader) (io.ReadCloser, bool, error) {
+	buffered := bufio.NewReader(reader)
+	magic, err := buffered.Peek(len(zstdMagic))
+	if err != nil && err != io.EOF {
+		return nil, false, err
+	}
+	switch {
+	case bytes.HasPrefix(magic, gzipMagic):
+		gz, err := gzip.NewReader(buffered)
+		return gz, true, err
+	case bytes.HasPrefix(magic, bzip2Magic):
+		return io.NopCloser(bzip2.NewReader(buffered)), true, nil
+	case bytes.HasPrefix(magic, zstdMagic):
+		zr, err := zstd.NewReader(buffered)
+		if err != nil {
+			return nil, true, err
+		}
+		return zr.IOReadCloser(), true, nil
+	default:
+		return io.NopCloser(buffered), false, nil
+	}
+}
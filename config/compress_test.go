@@ -0,0 +1,51 @@
+package config
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"testing"
+
+	"github.com/klauspost/compress/zstd"
+	. "github.com/torarvid/gloglog/testutil"
+)
+
+func TestDecompress(t *testing.T) {
+	// Two concatenated gzip members
+	var gz bytes.Buffer
+	for _, member := range []string{"one\n", "two\n"} {
+		w := gzip.NewWriter(&gz)
+		w.Write([]byte(member))
+		w.Close()
+	}
+
+	var zst bytes.Buffer
+	w, err := zstd.NewWriter(&zst)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.Write([]byte("one\ntwo\n"))
+	w.Close()
+
+	for _, input := range [][]byte{gz.Bytes(), zst.Bytes()} {
+		reader, compressed, err := decompress(bytes.NewReader(input))
+		if err != nil {
+			t.Fatal(err)
+		}
+		AssertEq(t, true, compressed)
+		data, err := io.ReadAll(reader)
+		if err != nil {
+			t.Fatal(err)
+		}
+		AssertEq(t, "one\ntwo\n", string(data))
+		AssertEq(t, nil, reader.Close())
+	}
+
+	reader, compressed, err := decompress(bytes.NewReader([]byte("x")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	AssertEq(t, false, compressed)
+	data, _ := io.ReadAll(reader)
+	AssertEq(t, "x", string(data))
+}
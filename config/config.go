@@ -3,33 +3,51 @@ package config
 import (
 	"fmt"
 	"io"
+	"log/slog"
 	"os"
 	"path"
+	"strconv"
 
 	"github.com/pelletier/go-toml/v2"
 )
 
+// Config is what is stored in config.toml.
+//
+// Patterns are named grok-style patterns that pattern(name, field) selector steps can use, in
+// addition to the built-in ones. For example:
+//
+//	[Patterns]
+//	myapp = '%{TIMESTAMP_ISO8601:time} \[%{LOGLEVEL:level}\] %{GREEDYDATA:message}'
 type Config struct {
 	SavedViews []*LogView
+	Patterns   map[string]string `toml:",omitempty"`
 	activeView *LogView
 }
 
 // LogView represents a named view of a log source.
 //
-// A SourceId can be "file", [or in the future...] a database, a remote service like Logstash or
-// Loki etc.
+// A SourceId can be "file" (a single file, or a glob pattern or directory for several files),
+// "stdin", "command", "loki", "elasticsearch" (which also reads OpenSearch), "sql" (a SQLite
+// database), "syslog" (a syslog receiver), "otlp" (an OpenTelemetry logs receiver), "journald"
+// (journalctl export or JSON output), "csv" (a CSV or TSV file) [or in the future...] other remote
+// services etc.
 //
 // Some of these sources require additional options to be specified; Options can be used for that.
+// Sources that can keep reading as new rows arrive do so when Options["follow"] is "true".
 //
 // Attributes are the columns that are shown in the view.
 //
-// Filters are used to filter which rows are shown.
+// Filters are used to filter which rows are shown. TimeRange, if set, limits the rows to a span of
+// time before they are filtered. Context is how many rows before and after each row that passes
+// the filters are shown too, like grep -C.
 type LogView struct {
-	Name     string
-	SourceId string
-	Options  map[string]string
-	Attrs    []Attribute
-	Filters  []Filter
+	Name      string
+	SourceId  string
+	Options   map[string]string
+	Attrs     []Attribute
+	Filters   []Filter
+	TimeRange *TimeRange `toml:",omitempty"`
+	Context   int        `toml:",omitempty"`
 }
 
 func (lv LogView) GetAttributeWithName(name string) (*Attribute, error) {
@@ -41,15 +59,89 @@ func (lv LogView) GetAttributeWithName(name string) (*Attribute, error) {
 	return nil, fmt.Errorf("no attribute with name %s", name)
 }
 
-func (lv LogView) GetRows() []string {
+// MergeAttr returns the attribute used to order rows when a view reads from several files. It is
+// the attribute named by Options["merge_by"], or else the first attribute of type time. It returns
+// nil if there is no such attribute.
+func (lv LogView) MergeAttr() *Attribute {
+	if name, ok := lv.Options["merge_by"]; ok {
+		attr, err := lv.GetAttributeWithName(name)
+		if err != nil {
+			slog.Error(err.Error())
+		}
+		return attr
+	}
+	for _, attr := range lv.Attrs {
+		if attr.Type == "time" {
+			return &attr
+		}
+	}
+	return nil
+}
+
+// ReadsManyFiles reports whether the view reads from several files, whose rows are merged by
+// MergeAttr and shown along with the file they came from.
+func (lv LogView) ReadsManyFiles() bool {
+	return lv.SourceId == "file" && namesManyFiles(lv.Options["filename"])
+}
+
+// GetSource returns the source the view reads from. Rows written by a container runtime are
+// unwrapped if Options["container"] says so, and lines are grouped into records if the view has
+// multiline options.
+func (lv LogView) GetSource() Source {
+	return withMultiline(lv, withContainerFormat(lv, lv.source()))
+}
+
+func (lv LogView) source() Source {
 	switch lv.SourceId {
 	case "file":
+		if lv.ReadsManyFiles() {
+			return fromFiles(lv)
+		}
 		return fromFile(lv)
+	case "stdin":
+		return fromStdin(lv)
+	case "command":
+		return fromCommand(lv)
+	case "loki":
+		return fromLoki(lv)
+	case "elasticsearch":
+		return fromElasticsearch(lv)
+	case "sql":
+		return fromSQL(lv)
+	case "syslog":
+		return fromSyslog(lv)
+	case "otlp":
+		return fromOTLP(lv)
+	case "csv":
+		return fromCSV(lv)
+	case "journald":
+		if readsJournalStdin(lv) {
+			return fromJournalStdin(lv)
+		}
+		return fromJournal(lv)
 	default:
 		panic("Unknown source id: " + lv.SourceId)
 	}
 }
 
+// Following reports whether the view is set up to keep reading new rows from its source, i.e.
+// whether Options["follow"] is true. Sources that only ever stream, like commands, follow by
+// default, and those that read standard input always do, as that is the only way they get rows.
+func (lv LogView) Following() bool {
+	if lv.SourceId == "stdin" || (lv.SourceId == "journald" && readsJournalStdin(lv)) {
+		return true
+	}
+	follow, err := strconv.ParseBool(lv.Options["follow"])
+	if err != nil {
+		switch lv.SourceId {
+		case "command", "syslog", "otlp":
+			return true
+		}
+		return false
+	}
+	return follow
+}
+
 // global config 🤘
 var TheConfig *Config
 
@@ -171,8 +263,21 @@ func ParseFilterOp(input string) (FilterOp, error) {
 	}
 }
 
+// Filter is a condition that rows must meet to be shown. It is either a comparison of Term with the
+// value of Attr (or the whole row if Attr is nil) using Operator, or a group of filters: Any passes
+// if one of its filters does, All if all of them do. Not negates the filter.
+//
+// A view's Filters must all pass, so groups are only needed for queries like "a or b".
 type Filter struct {
 	Term     string
 	Operator FilterOp
 	Attr     *Attribute
+	Not      bool     `toml:",omitempty"`
+	Any      []Filter `toml:",omitempty"`
+	All      []Filter `toml:",omitempty"`
+}
+
+// IsGroup reports whether the filter combines other filters rather than comparing a value.
+func (f Filter) IsGroup() bool {
+	return len(f.Any) > 0 || len(f.All) > 0
 }
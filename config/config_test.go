@@ -81,3 +81,36 @@ func TestSaveConfig(t *testing.T) {
 	expectedToml += "\n[[SavedViews.Filters]]\nTerm = 'foo'\nOperator = ''\n"
 	AssertEq(t, expectedToml, writer.String())
 }
+
+func TestReadingPipedInput(t *testing.T) {
+	saved := LogView{SourceId: "file", Options: map[string]string{"filename": "a.log", "follow": "false"}}
+	view := saved.ReadingPipedInput()
+	AssertEq(t, "stdin", view.SourceId)
+	AssertEq(t, true, view.Following())
+	view.Options["follow"] = "true"
+	AssertEq(t, "false", saved.Options["follow"])
+
+	journal := LogView{SourceId: "journald", Options: map[string]string{"filename": "j.export", "multiline": "json"}}
+	view = journal.ReadingPipedInput()
+	AssertEq(t, "journald", view.SourceId)
+	AssertEq(t, "-", view.Options["filename"])
+	AssertEq(t, "json", view.Options["multiline"])
+	AssertEq(t, "j.export", journal.Options["filename"])
+	AssertEq(t, true, view.Following())
+	source := view.GetSource()
+	if _, ok := source.(Follower); !ok {
+		t.Error("Expected the piped journal to be followed")
+	}
+	if suggester, ok := source.(AttrSuggester); !ok || len(suggester.SuggestedAttrs()) == 0 {
+		t.Error("Expected the piped journal to suggest attributes")
+	}
+}
+
+func TestLoadPatterns(t *testing.T) {
+	config := LoadFrom(strings.NewReader(validToml + "\n[Patterns]\nmyapp = '%{WORD:level} %{GREEDYDATA:msg}'\n"))
+	AssertEq(t, "%{WORD:level} %{GREEDYDATA:msg}", config.Patterns["myapp"])
+
+	writer := &strings.Builder{}
+	config.SaveTo(writer)
+	AssertEq(t, true, strings.Contains(writer.String(), "[Patterns]\nmyapp = '%{WORD:level} %{GREEDYDATA:msg}'"))
+}
@@ -0,0 +1,144 @@
+package config
+
+import (
+	"encoding/json"
+	"strings"
+	"sync"
+	"time"
+)
+
+// containerRows unwraps rows written by a container runtime, for views with Options["container"]
+// set. Docker's json-file driver writes each line as {"log": "...", "stream": ..., "time": ...},
+// and CRI runtimes (as used by Kubernetes) as "<time> <stream> <P|F> <message>". Both split long
+// lines into partial ones, which are put back together here.
+//
+// Options["container"] is "docker", "cri" or "auto", which recognizes either format line by line.
+// Each row becomes just the message the container wrote, so that selectors apply to the
+// application's own output. Lines in neither format are kept as they are.
+type containerRows struct {
+	format string
+
+	mu sync.Mutex
+	// partial holds the start of a line that was split up, by origin and stream.
+	partial map[string]string
+}
+
+func withContainerFormat(logView LogView, source Source) Source {
+	format := logView.Options["container"]
+	if format == "" {
+		return source
+	}
+	if format != "docker" && format != "cri" && format != "auto" {
+		panic("Unknown container format: " + format)
+	}
+	return decorate(source, newContainerRows(format))
+}
+
+func newContainerRows(format string) *containerRows {
+	return &containerRows{format: format, partial: make(map[string]string)}
+}
+
+// decorate unwraps rows. Partial lines are kept until the rest of them arrives, however long that
+// takes, so they aren't held back to be flushed.
+func (cs *containerRows) decorate(rows []Row) ([]Row, bool) {
+	return cs.unwrap(rows), false
+}
+
+func (cs *containerRows) flush() []Row {
+	return nil
+}
+
+// unwrap returns the messages in rows, leaving out partial lines until they are complete.
+func (cs *containerRows) unwrap(rows []Row) []Row {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+	unwrapped := make([]Row, 0, len(rows))
+	for _, row := range rows {
+		if row.Marker {
+			unwrapped = append(unwrapped, row)
+			continue
+		}
+		line, ok := containerLine(row.Line, cs.format)
+		if !ok {
+			unwrapped = append(unwrapped, row)
+			continue
+		}
+		key := row.Origin + "\x00" + line.stream
+		message := cs.partial[key] + line.message
+		if line.partial {
+			cs.partial[key] = message
+			continue
+		}
+		delete(cs.partial, key)
+		unwrapped = append(unwrapped, Row{Line: message, Origin: row.Origin})
+	}
+	return unwrapped
+}
+
+type containerLogLine struct {
+	stream  string
+	message string
+	// partial is set when the line continues in the next one from the same stream.
+	partial bool
+}
+
+// containerLine parses line in the given format, reporting false if it isn't in that format.
+func containerLine(line, format string) (containerLogLine, bool) {
+	switch format {
+	case "docker":
+		return dockerLine(line)
+	case "cri":
+		return criLine(line)
+	default:
+		if parsed, ok := dockerLine(line); ok {
+			return parsed, true
+		}
+		return criLine(line)
+	}
+}
+
+// dockerLine parses a line written by Docker's json-file logging driver. Docker ends every complete
+// line with a newline, so a message without one is partial.
+func dockerLine(line string) (containerLogLine, bool) {
+	if !strings.HasPrefix(line, "{") {
+		return containerLogLine{}, false
+	}
+	var entry struct {
+		Log    *string `json:"log"`
+		Stream string  `json:"stream"`
+	}
+	if err := json.Unmarshal([]byte(line), &entry); err != nil || entry.Log == nil {
+		return containerLogLine{}, false
+	}
+	message := strings.TrimSuffix(*entry.Log, "\n")
+	return containerLogLine{
+		stream:  entry.Stream,
+		message: strings.TrimSuffix(message, "\r"),
+		partial: !strings.HasSuffix(*entry.Log, "\n"),
+	}, true
+}
+
+// criLine parses a line in the CRI logging format, "<time> <stream> <tag> <message>", where the tag
+// is P for a partial line and F for a full one.
+func criLine(line string) (containerLogLine, bool) {
+	fields := strings.SplitN(line, " ", 4)
+	if len(fields) < 3 {
+		return containerLogLine{}, false
+	}
+	if _, err := time.Parse(time.RFC3339Nano, fields[0]); err != nil {
+		return containerLogLine{}, false
+	}
+	if fields[1] != "stdout" && fields[1] != "stderr" {
+		return containerLogLine{}, false
+	}
+	// The tag may carry more flags after the first one, separated by colons
+	tag, _, _ := strings.Cut(fields[2], ":")
+	if tag != "P" && tag != "F" {
+		return containerLogLine{}, false
+	}
+	parsed := containerLogLine{stream: fields[1], partial: tag == "P"}
+	if len(fields) == 4 {
+		parsed.message = fields[3]
+	}
+	return parsed, true
+}
@@ -0,0 +1,65 @@
+package config
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	. "github.com/torarvid/gloglog/testutil"
+)
+
+func TestContainerFormats(t *testing.T) {
+	lines := []string{
+		`{"log":"{\"msg\": \"hello\"}\n","stream":"stdout","time":"2024-01-02T03:04:05.000000001Z"}`,
+		`{"log":"a long ","stream":"stdout","time":"2024-01-02T03:04:05.000000002Z"}`,
+		`{"log":"error\n","stream":"stderr","time":"2024-01-02T03:04:05.000000003Z"}`,
+		`{"log":"line\n","stream":"stdout","time":"2024-01-02T03:04:05.000000004Z"}`,
+		`2024-01-02T03:04:06.000000001Z stdout P {"msg": `,
+		`2024-01-02T03:04:06.000000002Z stdout F "split"}`,
+		`2024-01-02T03:04:06.000000003Z stderr F `,
+		`not from a container`,
+	}
+	rows := newContainerRows("auto").unwrap(rowsFromLines(lines))
+
+	messages := make([]string, len(rows))
+	for i, row := range rows {
+		messages[i] = row.Line
+	}
+	AssertEq(t, strings.Join([]string{
+		`{"msg": "hello"}`,
+		"error",
+		"a long line",
+		`{"msg": "split"}`,
+		"",
+		"not from a container",
+	}, "\n"), strings.Join(messages, "\n"))
+
+	AssertEq(t, lines[0], newContainerRows("cri").unwrap(rowsFromLines(lines[:1]))[0].Line)
+}
+
+func TestContainerFollow(t *testing.T) {
+	followInterval = 10 * time.Millisecond
+	filename := filepath.Join(t.TempDir(), "container.log")
+	appendTo(t, filename, "2024-01-02T03:04:05Z stdout F one\n2024-01-02T03:04:05Z stdout P tw\n")
+
+	logView := LogView{SourceId: "file", Options: map[string]string{"filename": filename, "container": "cri"}}
+	source := logView.GetSource()
+	rows, err := source.Rows()
+	if err != nil {
+		t.Fatal(err)
+	}
+	AssertEq(t, 1, len(rows))
+	AssertEq(t, "one", rows[0].Line)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	updates := make(chan Update)
+	go source.(Follower).Follow(ctx, updates)
+
+	// The partial line read up front is completed by the followed one
+	appendTo(t, filename, "2024-01-02T03:04:06Z stdout F o\n")
+	rows = collectRows(updates, 1)
+	AssertEq(t, "two", rows[0].Line)
+}
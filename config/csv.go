@@ -0,0 +1,226 @@
+package config
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// csvSource reads a CSV (or TSV) file, where each record becomes a JSON object keyed by column
+// name, in column order.
+//
+// Options:
+//   - filename: the file to read, which may be compressed
+//   - delimiter: the character between fields; "," by default, or a tab for .tsv files. "tab" or
+//     "\t" also mean a tab.
+//   - quote: the character that quotes fields, where two of them stand for one; `"` by default, or
+//     "none" for no quoting
+//   - header: whether the first record names the columns (the default). Without a header, the
+//     columns are named column1, column2 and so on.
+//
+// A view without attributes gets one attribute per column.
+type csvSource struct {
+	filename  string
+	delimiter rune
+	quote     rune
+	header    bool
+
+	columns []string
+	samples [][]string
+}
+
+// csvSampleSize is how many records are looked at when suggesting attributes.
+const csvSampleSize = 100
+
+func fromCSV(logView LogView) *csvSource {
+	filename, exists := logView.Options["filename"]
+	if !exists {
+		panic("filename not found")
+	}
+	cs := &csvSource{filename: filename, delimiter: ',', quote: '"', header: true}
+	if strings.HasSuffix(filename, ".tsv") || strings.HasSuffix(filename, ".tab") {
+		cs.delimiter = '\t'
+	}
+	switch delimiter := logView.Options["delimiter"]; delimiter {
+	case "":
+	case "tab", `\t`:
+		cs.delimiter = '\t'
+	default:
+		if utf8.RuneCountInString(delimiter) != 1 {
+			panic("Invalid delimiter: " + delimiter)
+		}
+		cs.delimiter, _ = utf8.DecodeRuneInString(delimiter)
+	}
+	switch quote := logView.Options["quote"]; quote {
+	case "":
+	case "none":
+		cs.quote = 0
+	default:
+		if utf8.RuneCountInString(quote) != 1 {
+			panic("Invalid quote: " + quote)
+		}
+		cs.quote, _ = utf8.DecodeRuneInString(quote)
+	}
+	if header, exists := logView.Options["header"]; exists {
+		var err error
+		cs.header, err = strconv.ParseBool(header)
+		if err != nil {
+			panic("Invalid header option: " + header)
+		}
+	}
+	return cs
+}
+
+func (cs *csvSource) Rows() ([]Row, error) {
+	fmt.Fprintf(os.Stderr, "Scanning '%s'", cs.filename)
+	file, err := os.Open(cs.filename)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	decompressed, _, err := decompress(file)
+	if err != nil {
+		return nil, err
+	}
+	defer decompressed.Close()
+	reader := bufio.NewReader(decompressed)
+
+	rows := make([]Row, 0)
+	cs.columns, cs.samples = nil, nil
+	for {
+		record, err := readCSVRecord(reader, cs.delimiter, cs.quote)
+		if errors.Is(err, io.EOF) {
+			return rows, nil
+		}
+		if err != nil {
+			return rows, err
+		}
+		if len(record) == 1 && record[0] == "" {
+			continue
+		}
+		if cs.columns == nil && cs.header {
+			cs.columns = csvColumns(record)
+			continue
+		}
+		for len(cs.columns) < len(record) {
+			cs.columns = append(cs.columns, "column"+strconv.Itoa(len(cs.columns)+1))
+		}
+		if len(cs.samples) < csvSampleSize {
+			cs.samples = append(cs.samples, record)
+		}
+		values := make([]any, len(record))
+		for i, value := range record {
+			values[i] = value
+		}
+		line, err := jsonObject(cs.columns[:len(record)], values)
+		if err != nil {
+			return rows, err
+		}
+		rows = append(rows, Row{Line: line})
+	}
+}
+
+// SuggestedAttrs returns an attribute for each column, as wide as the values seen in the first
+// records allow. Columns where all of those values are times get the time type.
+func (cs *csvSource) SuggestedAttrs() []Attribute {
+	attrs := make([]Attribute, len(cs.columns))
+	for i, column := range cs.columns {
+		width, times, values := utf8.RuneCountInString(column), 0, 0
+		for _, record := range cs.samples {
+			if i >= len(record) || record[i] == "" {
+				continue
+			}
+			values++
+			width = max(width, utf8.RuneCountInString(record[i]))
+			if _, err := time.Parse(time.RFC3339, record[i]); err == nil {
+				times++
+			}
+		}
+		attrs[i] = Attribute{
+			Name:      column,
+			Width:     min(max(width, 4), 40),
+			Selectors: []string{"json(" + escapeJSONPath(column) + ")"},
+		}
+		if values > 0 && times == values {
+			attrs[i].Type = "time"
+		}
+	}
+	return attrs
+}
+
+// csvColumns names the columns after the header, naming those with an empty header by position.
+func csvColumns(header []string) []string {
+	columns := make([]string, len(header))
+	for i, name := range header {
+		name = strings.TrimPrefix(strings.TrimSpace(name), "\ufeff")
+		if name == "" {
+			name = "column" + strconv.Itoa(i+1)
+		}
+		columns[i] = name
+	}
+	return columns
+}
+
+// escapeJSONPath escapes the characters that have special meaning in a gjson path.
+func escapeJSONPath(key string) string {
+	var b strings.Builder
+	for _, r := range key {
+		if strings.ContainsRune(`.*?|#@\!`, r) {
+			b.WriteByte('\\')
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// readCSVRecord reads the fields of one record. Quoted fields may contain delimiters, newlines and
+// doubled quotes. A quote of 0 turns quoting off. It returns io.EOF when there are no more records.
+func readCSVRecord(reader *bufio.Reader, delimiter, quote rune) ([]string, error) {
+	fields := make([]string, 0)
+	var field strings.Builder
+	inQuotes, read := false, false
+	for {
+		r, _, err := reader.ReadRune()
+		if errors.Is(err, io.EOF) {
+			if !read {
+				return nil, io.EOF
+			}
+			if inQuotes {
+				return nil, errors.New("unterminated quoted field")
+			}
+			return append(fields, field.String()), nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		read = true
+		switch {
+		case inQuotes:
+			if r != quote {
+				field.WriteRune(r)
+			} else if next, _, err := reader.ReadRune(); err == nil && next == quote {
+				field.WriteRune(quote)
+			} else {
+				if err == nil {
+					reader.UnreadRune()
+				}
+				inQuotes = false
+			}
+		case r == quote && quote != 0 && field.Len() == 0:
+			inQuotes = true
+		case r == delimiter:
+			fields = append(fields, field.String())
+			field.Reset()
+		case r == '\n':
+			return append(fields, strings.TrimSuffix(field.String(), "\r")), nil
+		default:
+			field.WriteRune(r)
+		}
+	}
+}
@@ -0,0 +1,50 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/tidwall/gjson"
+	. "github.com/torarvid/gloglog/testutil"
+)
+
+func TestCSVSource(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "export.csv")
+	data := "time,level,user.name\r\n" +
+		"2024-01-02T03:04:05Z,info,\"Doe, Jane\"\r\n" +
+		"\n" +
+		"2024-01-02T03:04:06Z,error,\"said \"\"hi\"\"\nand left\",extra\n"
+	if err := os.WriteFile(filename, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	source := fromCSV(LogView{Options: map[string]string{"filename": filename}})
+	rows, err := source.Rows()
+	if err != nil {
+		t.Fatal(err)
+	}
+	AssertEq(t, 2, len(rows))
+	AssertEq(t, `{"time":"2024-01-02T03:04:05Z","level":"info","user.name":"Doe, Jane"}`, rows[0].Line)
+	AssertEq(t, "said \"hi\"\nand left", gjson.Get(rows[1].Line, `user\.name`).String())
+	AssertEq(t, "extra", gjson.Get(rows[1].Line, "column4").String())
+
+	attrs := source.SuggestedAttrs()
+	AssertEq(t, 4, len(attrs))
+	AssertEq(t, "time", attrs[0].Type)
+	AssertEq(t, "", attrs[1].Type)
+	AssertEq(t, 5, attrs[1].Width)
+	AssertEq(t, `json(user\.name)`, attrs[2].Selectors[0])
+	AssertEq(t, "column4", attrs[3].Name)
+
+	tsv := filepath.Join(t.TempDir(), "export.tsv")
+	if err := os.WriteFile(tsv, []byte("a\t'b\tc'\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	options := map[string]string{"filename": tsv, "quote": "'", "header": "false"}
+	rows, err = fromCSV(LogView{Options: options}).Rows()
+	if err != nil {
+		t.Fatal(err)
+	}
+	AssertEq(t, `{"column1":"a","column2":"b\tc"}`, rows[0].Line)
+}
@@ -0,0 +1,133 @@
+package config
+
+import (
+	"context"
+	"time"
+)
+
+// rowDecorator changes the rows read from a source, like containerRows, which unwraps the lines
+// written by a container runtime, and multilineRows, which groups lines into records.
+//
+// decorate may hold back rows that can still change as more rows arrive, and reports whether it
+// does. flush returns the rows that are held back. Both may be called by several goroutines.
+type rowDecorator interface {
+	decorate(rows []Row) (decorated []Row, holding bool)
+	flush() []Row
+}
+
+// flushDelay is how long rows held back by a decorator of a followed source wait for more rows
+// before they are flushed anyway.
+var flushDelay = 200 * time.Millisecond
+
+// decoratedSource is a source whose rows are changed by a rowDecorator. It suggests the attributes
+// its source suggests, if any.
+type decoratedSource struct {
+	Source
+	decorator rowDecorator
+}
+
+// decoratedFollower is a decoratedSource whose source can be followed.
+type decoratedFollower struct {
+	*decoratedSource
+	follower Follower
+}
+
+// decoratedPager is a decoratedSource whose source loads rows a page at a time. Rows held back at
+// the end of a page are kept for the next one, and flushed after the last.
+type decoratedPager struct {
+	*decoratedSource
+	pager Pager
+}
+
+// decorate returns source with its rows changed by decorator. The result can be followed or paged
+// through if source can; no source does both.
+func decorate(source Source, decorator rowDecorator) Source {
+	ds := &decoratedSource{Source: source, decorator: decorator}
+	switch s := source.(type) {
+	case Follower:
+		return &decoratedFollower{decoratedSource: ds, follower: s}
+	case Pager:
+		return &decoratedPager{decoratedSource: ds, pager: s}
+	}
+	return ds
+}
+
+func (ds *decoratedSource) Rows() ([]Row, error) {
+	rows, err := ds.Source.Rows()
+	decorated, _ := ds.decorator.decorate(rows)
+	return append(decorated, ds.decorator.flush()...), err
+}
+
+func (ds *decoratedSource) SuggestedAttrs() []Attribute {
+	if suggester, ok := ds.Source.(AttrSuggester); ok {
+		return suggester.SuggestedAttrs()
+	}
+	return nil
+}
+
+func (dp *decoratedPager) Rows() ([]Row, error) {
+	rows, err := dp.Source.Rows()
+	decorated, _ := dp.decorator.decorate(rows)
+	return decorated, err
+}
+
+// NextPage returns the rows of the source's next page, or of the pages after it if they are all
+// held back, as no rows means there are no more pages.
+func (dp *decoratedPager) NextPage() ([]Row, error) {
+	for {
+		rows, err := dp.pager.NextPage()
+		if err != nil {
+			return nil, err
+		}
+		if len(rows) == 0 {
+			return dp.decorator.flush(), nil
+		}
+		if decorated, _ := dp.decorator.decorate(rows); len(decorated) > 0 {
+			return decorated, nil
+		}
+	}
+}
+
+// Follow follows the source, decorating the rows of each update. Rows that are held back are
+// flushed after flushDelay unless more rows arrive, and when the source is done.
+func (df *decoratedFollower) Follow(ctx context.Context, updates chan<- Update) error {
+	wrapped := make(chan Update)
+	result := make(chan error, 1)
+	go func() {
+		defer close(wrapped)
+		result <- df.follower.Follow(ctx, wrapped)
+	}()
+	var flushTimer <-chan time.Time
+	for {
+		var update Update
+		select {
+		case u, ok := <-wrapped:
+			if !ok {
+				if rows := df.decorator.flush(); len(rows) > 0 {
+					send(ctx, updates, Update{Rows: rows})
+				}
+				return <-result
+			}
+			var holding bool
+			u.Rows, holding = df.decorator.decorate(u.Rows)
+			// The rows held back are likely to change soon
+			flushTimer = nil
+			if holding {
+				flushTimer = time.After(flushDelay)
+			}
+			update = u
+		case <-flushTimer:
+			flushTimer = nil
+			update.Rows = df.decorator.flush()
+		}
+		if len(update.Rows) == 0 && update.Status == "" {
+			continue
+		}
+		if !send(ctx, updates, update) {
+			break
+		}
+	}
+	for range wrapped {
+	}
+	return <-result
+}
@@ -0,0 +1,60 @@
+package config
+
+import (
+	"testing"
+
+	. "github.com/torarvid/gloglog/testutil"
+)
+
+// pagedSource returns its pages one at a time, and suggests an attribute.
+type pagedSource struct {
+	pages [][]Row
+}
+
+func (ps *pagedSource) Rows() ([]Row, error) {
+	return ps.NextPage()
+}
+
+func (ps *pagedSource) NextPage() ([]Row, error) {
+	if len(ps.pages) == 0 {
+		return nil, nil
+	}
+	page := ps.pages[0]
+	ps.pages = ps.pages[1:]
+	return page, nil
+}
+
+func (ps *pagedSource) SuggestedAttrs() []Attribute {
+	return []Attribute{{Name: "Message"}}
+}
+
+func TestDecoratedPager(t *testing.T) {
+	logView := LogView{Options: map[string]string{"multiline_continue": `^\s`}}
+	source := withMultiline(logView, &pagedSource{pages: [][]Row{
+		rowsFromLines([]string{"panic: oops", "  goroutine 1"}),
+		rowsFromLines([]string{"  main.go:3"}),
+		rowsFromLines([]string{"  main.go:4", "next"}),
+	}})
+
+	suggester, ok := source.(AttrSuggester)
+	if !ok {
+		t.Fatal("Expected the decorated source to suggest attributes")
+	}
+	AssertEq(t, "Message", suggester.SuggestedAttrs()[0].Name)
+
+	pager, ok := source.(Pager)
+	if !ok {
+		t.Fatal("Expected the decorated source to load pages")
+	}
+	rows, _ := pager.Rows()
+	AssertEq(t, 0, len(rows))
+	// A record that goes on over the next pages is completed there
+	rows, _ = pager.NextPage()
+	AssertEq(t, 1, len(rows))
+	AssertEq(t, "panic: oops\n  goroutine 1\n  main.go:3\n  main.go:4", rows[0].Line)
+	rows, _ = pager.NextPage()
+	AssertEq(t, 1, len(rows))
+	AssertEq(t, "next", rows[0].Line)
+	rows, _ = pager.NextPage()
+	AssertEq(t, 0, len(rows))
+}
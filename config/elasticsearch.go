@@ -0,0 +1,212 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// esKeepAlive is how long the point in time is kept open between pages.
+const esKeepAlive = "5m"
+
+// elasticsearchSource reads documents from an Elasticsearch or OpenSearch index, one page at a
+// time, as the user scrolls towards the end of what's been loaded.
+//
+// Options:
+//   - url: where the cluster is, e.g. http://localhost:9200
+//   - index: the index or index pattern, e.g. logs-*
+//   - query: a query in the query DSL, e.g. {"term": {"level": "error"}}
+//   - q: a Lucene query string, used if query isn't set (default everything)
+//   - time_field: the field to sort and filter on (default @timestamp)
+//   - start, end: the time range; RFC3339 times or durations relative to now (default -1h and now)
+//   - page_size: how many documents to load per page (default 500)
+//   - username, password: for basic authentication
+//
+// Pages are fetched with search_after within a point in time, so that they are consistent with
+// each other. Each row is the document's _source, and its Origin is the index it was found in.
+type elasticsearchSource struct {
+	url       string
+	index     string
+	query     json.RawMessage
+	timeField string
+	start     time.Time
+	end       time.Time
+	pageSize  int
+	username  string
+	password  string
+	client    *http.Client
+
+	// pitID is the point in time that all pages are read from, once opened.
+	pitID string
+	// openSearch is set when the cluster turned out to use OpenSearch's point in time API.
+	openSearch bool
+	// searchAfter is the sort values of the last document read.
+	searchAfter []any
+	exhausted   bool
+}
+
+type esSearchResponse struct {
+	PitID string `json:"pit_id"`
+	Hits  struct {
+		Hits []struct {
+			Index  string          `json:"_index"`
+			Source json.RawMessage `json:"_source"`
+			Sort   []any           `json:"sort"`
+		} `json:"hits"`
+	} `json:"hits"`
+}
+
+func fromElasticsearch(logView LogView) *elasticsearchSource {
+	esURL, exists := logView.Options["url"]
+	if !exists {
+		panic("url not found")
+	}
+	index, exists := logView.Options["index"]
+	if !exists {
+		panic("index not found")
+	}
+	query := json.RawMessage(`{"match_all": {}}`)
+	if dsl, exists := logView.Options["query"]; exists {
+		if !json.Valid([]byte(dsl)) {
+			panic("query is not valid JSON")
+		}
+		query = json.RawMessage(dsl)
+	} else if q, exists := logView.Options["q"]; exists {
+		query, _ = json.Marshal(map[string]any{"query_string": map[string]any{"query": q}})
+	}
+	timeField := logView.Options["time_field"]
+	if timeField == "" {
+		timeField = "@timestamp"
+	}
+	pageSize := 500
+	if value, exists := logView.Options["page_size"]; exists {
+		var err error
+		if pageSize, err = strconv.Atoi(value); err != nil {
+			panic(err)
+		}
+	}
+	now := time.Now()
+	return &elasticsearchSource{
+		url:       strings.TrimSuffix(esURL, "/"),
+		index:     index,
+		query:     query,
+		timeField: timeField,
+		start:     logView.timeOption("start", "-1h", now),
+		end:       logView.timeOption("end", "now", now),
+		pageSize:  pageSize,
+		username:  logView.Options["username"],
+		password:  logView.Options["password"],
+		client:    http.DefaultClient,
+	}
+}
+
+func (es *elasticsearchSource) Rows() ([]Row, error) {
+	fmt.Fprintf(os.Stderr, "Searching '%s' at '%s'", es.index, es.url)
+	if err := es.openPointInTime(); err != nil {
+		return nil, err
+	}
+	return es.NextPage()
+}
+
+func (es *elasticsearchSource) NextPage() ([]Row, error) {
+	if es.exhausted {
+		return []Row{}, nil
+	}
+	body := map[string]any{
+		"size": es.pageSize,
+		"query": map[string]any{
+			"bool": map[string]any{
+				"must": []any{es.query},
+				"filter": []any{map[string]any{
+					"range": map[string]any{
+						es.timeField: map[string]any{
+							"gte": es.start.Format(time.RFC3339Nano),
+							"lte": es.end.Format(time.RFC3339Nano),
+						},
+					},
+				}},
+			},
+		},
+		"sort": []any{map[string]any{es.timeField: "asc"}},
+		"pit":  map[string]any{"id": es.pitID, "keep_alive": esKeepAlive},
+	}
+	if es.searchAfter != nil {
+		body["search_after"] = es.searchAfter
+	}
+
+	var result esSearchResponse
+	if err := es.post("/_search", body, &result); err != nil {
+		return nil, err
+	}
+	if result.PitID != "" {
+		es.pitID = result.PitID
+	}
+	hits := result.Hits.Hits
+	rows := make([]Row, len(hits))
+	for i, hit := range hits {
+		rows[i] = Row{Line: string(hit.Source), Origin: hit.Index}
+	}
+	if len(hits) > 0 {
+		es.searchAfter = hits[len(hits)-1].Sort
+	}
+	es.exhausted = len(hits) < es.pageSize
+	return rows, nil
+}
+
+// openPointInTime opens a point in time for the index, using Elasticsearch's API or, failing that,
+// OpenSearch's.
+func (es *elasticsearchSource) openPointInTime() error {
+	var pit struct {
+		ID    string `json:"id"`
+		PitID string `json:"pit_id"`
+	}
+	err := es.post("/"+es.index+"/_pit?keep_alive="+esKeepAlive, nil, &pit)
+	if err != nil {
+		es.openSearch = true
+		path := "/" + es.index + "/_search/point_in_time?keep_alive=" + esKeepAlive
+		if osErr := es.post(path, nil, &pit); osErr != nil {
+			return fmt.Errorf("opening point in time: %w", err)
+		}
+	}
+	es.pitID = pit.ID
+	if es.openSearch {
+		es.pitID = pit.PitID
+	}
+	return nil
+}
+
+// post sends body as JSON to path and decodes the response into result.
+func (es *elasticsearchSource) post(path string, body any, result any) error {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(data)
+	}
+	req, err := http.NewRequest("POST", es.url+path, reader)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if es.username != "" {
+		req.SetBasicAuth(es.username, es.password)
+	}
+	resp, err := es.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		message, _ := io.ReadAll(io.LimitReader(resp.Body, 500))
+		return fmt.Errorf("%s %s: %s: %s", req.Method, path, resp.Status, message)
+	}
+	return json.NewDecoder(resp.Body).Decode(result)
+}
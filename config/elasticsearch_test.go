@@ -0,0 +1,84 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	. "github.com/torarvid/gloglog/testutil"
+)
+
+// fakeOpenSearch serves n documents through OpenSearch's point in time API.
+func fakeOpenSearch(t *testing.T, n int) *httptest.Server {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/logs-*/_search/point_in_time", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"pit_id": "pit-1"}`)
+	})
+	mux.HandleFunc("/_search", func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Size        int
+			Pit         struct{ ID string }
+			SearchAfter []float64 `json:"search_after"`
+			Query       struct {
+				Bool struct {
+					Must []map[string]any
+				}
+			}
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Fatal(err)
+		}
+		AssertEq(t, "pit-1", body.Pit.ID)
+		AssertEq(t, true, body.Query.Bool.Must[0]["query_string"] != nil)
+		from := 0
+		if len(body.SearchAfter) > 0 {
+			from = int(body.SearchAfter[0])
+		}
+		hits := make([]string, 0)
+		for i := from + 1; i <= n && len(hits) < body.Size; i++ {
+			hits = append(hits, fmt.Sprintf(
+				`{"_index": "logs-1", "_source": {"n": %d}, "sort": [%d]}`, i, i,
+			))
+		}
+		fmt.Fprintf(w, `{"pit_id": "pit-1", "hits": {"hits": [%s]}}`, strings.Join(hits, ","))
+	})
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		http.NotFound(w, r)
+	})
+	return httptest.NewServer(mux)
+}
+
+func TestElasticsearchSource(t *testing.T) {
+	server := fakeOpenSearch(t, 5)
+	defer server.Close()
+
+	source := fromElasticsearch(LogView{Options: map[string]string{
+		"url":       server.URL,
+		"index":     "logs-*",
+		"q":         "level:error",
+		"page_size": "2",
+	}})
+	rows, err := source.Rows()
+	if err != nil {
+		t.Fatal(err)
+	}
+	AssertEq(t, 2, len(rows))
+	AssertEq(t, `{"n": 1}`, rows[0].Line)
+	AssertEq(t, "logs-1", rows[0].Origin)
+
+	total := len(rows)
+	for {
+		rows, err = source.NextPage()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(rows) == 0 {
+			break
+		}
+		total += len(rows)
+	}
+	AssertEq(t, 5, total)
+}
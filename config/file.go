@@ -2,28 +2,216 @@ package config
 
 import (
 	"bufio"
+	"context"
+	"errors"
 	"fmt"
-	"log/slog"
+	"io"
 	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
 )
 
-func fromFile(logView LogView) []string {
+// followInterval is how often a followed file is checked for new data.
+var followInterval = 250 * time.Millisecond
+
+type fileSource struct {
+	filename    string
+	readRotated bool
+	// follow is set if the view follows the file, in which case Rows leaves out a last line that
+	// isn't terminated yet, as Follow will read it once it is. Otherwise Rows includes the line and
+	// the offset is moved past it, so that following the file later goes on from there.
+	follow bool
+
+	mu sync.Mutex
+	// offset is the position in the file right after the last line that was read.
+	offset int64
+	// compressed is set by Rows if the file turned out to be compressed.
+	compressed bool
+}
+
+func fromFile(logView LogView) *fileSource {
 	filename, exists := logView.Options["filename"]
 	if !exists {
 		panic("filename not found")
 	}
+	readRotated, err := strconv.ParseBool(logView.Options["read_rotated"])
+	if err != nil {
+		readRotated = true
+	}
+	return &fileSource{filename: filename, readRotated: readRotated, follow: logView.Following()}
+}
+
+func (fs *fileSource) Rows() ([]Row, error) {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	fmt.Fprintf(os.Stderr, "Scanning file '%s'", fs.filename)
+	lines, n, partial, compressed, err := readFile(fs.filename)
+	if err != nil {
+		return nil, err
+	}
+	fs.offset, fs.compressed = n, compressed
+	// A file that doesn't end with a newline still has a last row; include it like a scanner
+	// would, unless it is followed and the rest of the line is yet to come.
+	if partial != "" && (!fs.follow || compressed) {
+		lines = append(lines, trimEOL(partial))
+		fs.offset += int64(len(partial))
+	}
+	return rowsFromLines(lines), nil
+}
+
+// readFile reads all complete lines of a file, decompressing it if needed. It also returns how many
+// bytes they took up, any last line that isn't terminated by a newline (as is), and whether the
+// file was compressed.
+func readFile(filename string) (lines []string, n int64, partial string, compressed bool, err error) {
 	file, err := os.Open(filename)
 	if err != nil {
-		slog.Error(err.Error())
-		panic(err)
+		return nil, 0, "", false, err
 	}
 	defer file.Close()
 
-	scanner := bufio.NewScanner(file)
-	fmt.Fprintf(os.Stderr, "Scanning file '%s'", filename)
-	rows := make([]string, 0)
-	for scanner.Scan() {
-		rows = append(rows, scanner.Text())
+	reader, compressed, err := decompress(file)
+	if err != nil {
+		return nil, 0, "", false, err
+	}
+	defer reader.Close()
+	lines, n, partial, err = readLines(bufio.NewReader(reader))
+	if err != nil {
+		return nil, 0, "", false, err
+	}
+	return lines, n, partial, compressed, nil
+}
+
+// Follow polls the file for lines appended after the last read.
+//
+// It also notices when the file is rotated, either by being renamed and replaced by a new file or by
+// being truncated, and then continues reading from the start of the new file. Unless
+// Options["read_rotated"] is false, whatever was written to a renamed file after the last poll is
+// read before moving on. A marker row is inserted where the rotation happened.
+//
+// Compressed files can't be followed.
+func (fs *fileSource) Follow(ctx context.Context, updates chan<- Update) error {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	if fs.compressed {
+		return fmt.Errorf("can't follow compressed file '%s'", fs.filename)
+	}
+
+	file, err := os.Open(fs.filename)
+	if err != nil {
+		return err
+	}
+	defer func() { file.Close() }()
+
+	ticker := time.NewTicker(followInterval)
+	defer ticker.Stop()
+	for {
+		lines, _, err := fs.readFrom(file)
+		if err != nil {
+			return err
+		}
+		rows := rowsFromLines(lines)
+
+		rotated, truncated, err := fs.rotation(file)
+		if err != nil {
+			return err
+		}
+		if rotated {
+			if fs.readRotated {
+				more, partial, err := fs.readFrom(file)
+				if err != nil {
+					return err
+				}
+				rows = append(rows, rowsFromLines(more)...)
+				if partial != "" {
+					rows = append(rows, Row{Line: trimEOL(partial)})
+				}
+			}
+			newFile, err := os.Open(fs.filename)
+			if err != nil {
+				return err
+			}
+			file.Close()
+			file = newFile
+			rows = append(rows, MarkerRow(fmt.Sprintf("'%s' was rotated", fs.filename)))
+		} else if truncated {
+			rows = append(rows, MarkerRow(fmt.Sprintf("'%s' was truncated", fs.filename)))
+		}
+		if rotated || truncated {
+			fs.offset = 0
+			more, _, err := fs.readFrom(file)
+			if err != nil {
+				return err
+			}
+			rows = append(rows, rowsFromLines(more)...)
+		}
+
+		if len(rows) > 0 && !send(ctx, updates, Update{Rows: rows}) {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// readFrom reads the complete lines in file after the current offset, and moves the offset past
+// them. It also returns any trailing data that isn't terminated by a newline yet.
+func (fs *fileSource) readFrom(file *os.File) (lines []string, partial string, err error) {
+	if _, err := file.Seek(fs.offset, io.SeekStart); err != nil {
+		return nil, "", err
+	}
+	lines, n, partial, err := readLines(bufio.NewReader(file))
+	if err != nil {
+		return nil, "", err
 	}
-	return rows
+	fs.offset += n
+	return lines, partial, nil
+}
+
+// rotation checks whether the open file has been replaced by a new file with the same name, or if
+// it has been truncated to less than what has been read from it.
+func (fs *fileSource) rotation(file *os.File) (rotated, truncated bool, err error) {
+	info, err := file.Stat()
+	if err != nil {
+		return false, false, err
+	}
+	current, err := os.Stat(fs.filename)
+	if errors.Is(err, os.ErrNotExist) {
+		// Renamed, but the new file hasn't been created yet. Check again on the next poll.
+		return false, false, nil
+	}
+	if err != nil {
+		return false, false, err
+	}
+	if !os.SameFile(info, current) {
+		return true, false, nil
+	}
+	return false, info.Size() < fs.offset, nil
+}
+
+// readLines reads complete lines from reader until EOF. It returns the lines, the number of bytes
+// they occupied, and whatever trailing data was not terminated by a newline.
+func readLines(reader *bufio.Reader) (lines []string, n int64, partial string, err error) {
+	lines = make([]string, 0)
+	for {
+		line, err := reader.ReadString('\n')
+		if err == io.EOF {
+			return lines, n, line, nil
+		}
+		if err != nil {
+			return nil, 0, "", err
+		}
+		n += int64(len(line))
+		lines = append(lines, trimEOL(line))
+	}
+}
+
+func trimEOL(line string) string {
+	return strings.TrimSuffix(strings.TrimSuffix(line, "\n"), "\r")
 }
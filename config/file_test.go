@@ -0,0 +1,166 @@
+package config
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	. "github.com/torarvid/gloglog/testutil"
+)
+
+func TestFileFollow(t *testing.T) {
+	followInterval = 10 * time.Millisecond
+	filename := filepath.Join(t.TempDir(), "test.log")
+	if err := os.WriteFile(filename, []byte("one\ntwo\nthr"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	rows, err := fromFile(LogView{Options: map[string]string{"filename": filename}}).Rows()
+	if err != nil {
+		t.Fatal(err)
+	}
+	AssertEq(t, 3, len(rows))
+	AssertEq(t, "thr", rows[2].Line)
+
+	// When followed, the unterminated line is left for Follow to read once it is complete
+	source := fromFile(LogView{Options: map[string]string{"filename": filename, "follow": "true"}})
+	rows, err = source.Rows()
+	if err != nil {
+		t.Fatal(err)
+	}
+	AssertEq(t, 2, len(rows))
+	AssertEq(t, "two", rows[1].Line)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	updates := make(chan Update)
+	go source.Follow(ctx, updates)
+
+	file, err := os.OpenFile(filename, os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	file.WriteString("ee\r\nfour")
+
+	update := <-updates
+	AssertEq(t, 1, len(update.Rows))
+	AssertEq(t, "three", update.Rows[0].Line)
+
+	file.WriteString("\n")
+	update = <-updates
+	AssertEq(t, 1, len(update.Rows))
+	AssertEq(t, "four", update.Rows[0].Line)
+}
+
+func TestFileFollowLater(t *testing.T) {
+	followInterval = 10 * time.Millisecond
+	filename := filepath.Join(t.TempDir(), "test.log")
+	if err := os.WriteFile(filename, []byte("one\nthr"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	source := fromFile(LogView{Options: map[string]string{"filename": filename}})
+	rows, err := source.Rows()
+	if err != nil {
+		t.Fatal(err)
+	}
+	AssertEq(t, 2, len(rows))
+	AssertEq(t, "thr", rows[1].Line)
+
+	// Following is turned on after the unterminated line was shown; only the rest of it is new
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	updates := make(chan Update)
+	go source.Follow(ctx, updates)
+	appendTo(t, filename, "ee\nfour\n")
+
+	update := <-updates
+	AssertEq(t, 2, len(update.Rows))
+	AssertEq(t, "ee", update.Rows[0].Line)
+	AssertEq(t, "four", update.Rows[1].Line)
+}
+
+func TestFileFollowRotation(t *testing.T) {
+	followInterval = 10 * time.Millisecond
+	filename := filepath.Join(t.TempDir(), "test.log")
+	if err := os.WriteFile(filename, []byte("one\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	source := fromFile(LogView{Options: map[string]string{"filename": filename}})
+	if _, err := source.Rows(); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	updates := make(chan Update)
+	go source.Follow(ctx, updates)
+
+	// Make sure the follower has opened the original file before rotating it
+	appendTo(t, filename, "two\n")
+	AssertEq(t, "two", (<-updates).Rows[0].Line)
+
+	// rename + create
+	appendTo(t, filename, "three\n")
+	if err := os.Rename(filename, filename+".1"); err != nil {
+		t.Fatal(err)
+	}
+	appendTo(t, filename, "four\n")
+	rows := collectRows(updates, 3)
+	AssertEq(t, "three", rows[0].Line)
+	AssertEq(t, true, rows[1].Marker)
+	AssertEq(t, "'"+filename+"' was rotated", rows[1].Line)
+	AssertEq(t, "four", rows[2].Line)
+
+	// copytruncate
+	if err := os.Truncate(filename, 0); err != nil {
+		t.Fatal(err)
+	}
+	appendTo(t, filename, "5\n")
+	rows = collectRows(updates, 2)
+	AssertEq(t, "'"+filename+"' was truncated", rows[0].Line)
+	AssertEq(t, "5", rows[1].Line)
+}
+
+func appendTo(t *testing.T, filename, data string) {
+	file, err := os.OpenFile(filename, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	if _, err := file.WriteString(data); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// collectRows reads updates until at least n rows have arrived.
+func collectRows(updates <-chan Update, n int) []Row {
+	rows := make([]Row, 0, n)
+	for len(rows) < n {
+		rows = append(rows, (<-updates).Rows...)
+	}
+	return rows
+}
+
+func TestFilesSource(t *testing.T) {
+	dir := t.TempDir()
+	appendTo(t, filepath.Join(dir, "b.log"), "b1\nb2\n")
+	appendTo(t, filepath.Join(dir, "a.log"), "a1\n")
+	appendTo(t, filepath.Join(dir, "c.txt"), "c1\n")
+
+	for pattern, count := range map[string]int{dir: 4, filepath.Join(dir, "*.log"): 3} {
+		AssertEq(t, true, namesManyFiles(pattern))
+		rows, err := fromFiles(LogView{Options: map[string]string{"filename": pattern}}).Rows()
+		if err != nil {
+			t.Fatal(err)
+		}
+		AssertEq(t, count, len(rows))
+		AssertEq(t, "a1", rows[0].Line)
+		AssertEq(t, "a.log", rows[0].Origin)
+		AssertEq(t, "b.log", rows[2].Origin)
+	}
+	AssertEq(t, false, namesManyFiles(filepath.Join(dir, "a.log")))
+}
@@ -0,0 +1,92 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// filesSource reads several files at once, for views where Options["filename"] is a glob pattern
+// or a directory. Each row's Origin is the file it was read from.
+//
+// Rows come grouped by file, in file name order; it is up to the view to interleave them.
+type filesSource struct {
+	pattern string
+}
+
+// namesManyFiles reports whether filename is a glob pattern or a directory rather than a single
+// file.
+func namesManyFiles(filename string) bool {
+	if strings.ContainsAny(filename, "*?[") {
+		return true
+	}
+	info, err := os.Stat(filename)
+	return err == nil && info.IsDir()
+}
+
+func fromFiles(logView LogView) *filesSource {
+	pattern, exists := logView.Options["filename"]
+	if !exists {
+		panic("filename not found")
+	}
+	return &filesSource{pattern: pattern}
+}
+
+func (fs *filesSource) Rows() ([]Row, error) {
+	filenames, err := fs.filenames()
+	if err != nil {
+		return nil, err
+	}
+	fmt.Fprintf(os.Stderr, "Scanning %d files in '%s'", len(filenames), fs.pattern)
+	rows := make([]Row, 0)
+	for _, filename := range filenames {
+		lines, _, partial, _, err := readFile(filename)
+		if err != nil {
+			return nil, err
+		}
+		if partial != "" {
+			lines = append(lines, trimEOL(partial))
+		}
+		origin := filename
+		if rel, err := filepath.Rel(fs.dir(), filename); err == nil {
+			origin = rel
+		}
+		for _, line := range lines {
+			rows = append(rows, Row{Line: line, Origin: origin})
+		}
+	}
+	return rows, nil
+}
+
+// filenames returns the regular files matching the pattern, or in the directory, sorted by name.
+func (fs *filesSource) filenames() ([]string, error) {
+	pattern := fs.pattern
+	if info, err := os.Stat(pattern); err == nil && info.IsDir() {
+		pattern = filepath.Join(pattern, "*")
+	}
+	matches, err := filepath.Glob(pattern)
+	if err != nil {
+		return nil, err
+	}
+	filenames := make([]string, 0, len(matches))
+	for _, match := range matches {
+		if info, err := os.Stat(match); err == nil && info.Mode().IsRegular() {
+			filenames = append(filenames, match)
+		}
+	}
+	if len(filenames) == 0 {
+		return nil, fmt.Errorf("no files found in '%s'", fs.pattern)
+	}
+	return filenames, nil
+}
+
+// dir returns the directory that the files are shown relative to: the directory itself, or the
+// part of the glob pattern before the first wildcard.
+func (fs *filesSource) dir() string {
+	if info, err := os.Stat(fs.pattern); err == nil && info.IsDir() {
+		return fs.pattern
+	}
+	prefix := fs.pattern[:strings.IndexAny(fs.pattern, "*?[")]
+	return filepath.Dir(prefix + "x")
+}
@@ -0,0 +1,87 @@
+package config
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Matcher returns a function that tells whether a value, i.e. what the filter's attribute extracts
+// from a row (or the whole row if it has no attribute), passes the filter. Without an operator,
+// the filter checks that the value contains Term.
+//
+// == and != compare numbers as numbers and everything else as text. =~ and !~ match Term as a
+// regular expression. >, <, >= and <= compare numbers as numbers, times (for attributes of type
+// time, or values that are RFC3339 times) as times, and anything else as text. A time Term may be
+// relative to now, like "-15m".
+func (f Filter) Matcher() (func(value string) bool, error) {
+	term := f.Term
+	switch f.Operator {
+	case "", Contains:
+		return func(value string) bool { return strings.Contains(value, term) }, nil
+	case NotContains:
+		return func(value string) bool { return !strings.Contains(value, term) }, nil
+	case Equal:
+		return func(value string) bool { return compareValues(value, term, false) == 0 }, nil
+	case NotEqual:
+		return func(value string) bool { return compareValues(value, term, false) != 0 }, nil
+	case RegexEqual, RegexNotEqual:
+		re, err := regexp.Compile(term)
+		if err != nil {
+			return nil, fmt.Errorf("invalid regex %s: %w", term, err)
+		}
+		want := f.Operator == RegexEqual
+		return func(value string) bool { return re.MatchString(value) == want }, nil
+	case GreaterThan, LessThan, GreaterThanOrEqual, LessThanOrEqual:
+		isTime := f.Attr != nil && f.Attr.Type == "time"
+		var bound time.Time
+		if isTime {
+			var err error
+			if bound, err = ParseTimeBound(term, time.Now()); err != nil {
+				return nil, err
+			}
+			term = bound.Format(time.RFC3339Nano)
+		}
+		accept := map[FilterOp]func(int) bool{
+			GreaterThan:        func(c int) bool { return c > 0 },
+			LessThan:           func(c int) bool { return c < 0 },
+			GreaterThanOrEqual: func(c int) bool { return c >= 0 },
+			LessThanOrEqual:    func(c int) bool { return c <= 0 },
+		}[f.Operator]
+		return func(value string) bool {
+			if value == "" {
+				return false
+			}
+			return accept(compareValues(value, term, true))
+		}, nil
+	default:
+		return nil, fmt.Errorf("invalid filter op: %s", f.Operator)
+	}
+}
+
+// compareValues compares a and b as numbers if both are numbers, as times if both are times and
+// ordered is set, and as text otherwise. It returns -1, 0 or 1 like strings.Compare.
+func compareValues(a, b string, ordered bool) int {
+	if x, err := strconv.ParseFloat(a, 64); err == nil {
+		if y, err := strconv.ParseFloat(b, 64); err == nil {
+			switch {
+			case x < y:
+				return -1
+			case x > y:
+				return 1
+			default:
+				return 0
+			}
+		}
+	}
+	if ordered {
+		if x, err := time.Parse(time.RFC3339Nano, a); err == nil {
+			if y, err := time.Parse(time.RFC3339Nano, b); err == nil {
+				return x.Compare(y)
+			}
+		}
+	}
+	return strings.Compare(a, b)
+}
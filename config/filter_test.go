@@ -0,0 +1,47 @@
+package config
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/torarvid/gloglog/testutil"
+)
+
+func TestFilterMatcher(t *testing.T) {
+	timeAttr := &Attribute{Name: "Time", Type: "time"}
+	recent := time.Now().Add(-time.Minute).UTC().Format(time.RFC3339)
+	for _, test := range []struct {
+		filter  Filter
+		value   string
+		matches bool
+	}{
+		{Filter{Term: "err"}, "an error", true},
+		{Filter{Term: "err", Operator: NotContains}, "an error", false},
+		{Filter{Term: "error", Operator: Equal}, "error", true},
+		{Filter{Term: "200", Operator: Equal}, "200.0", true},
+		{Filter{Term: "200", Operator: NotEqual}, "404", true},
+		{Filter{Term: "^pay.*", Operator: RegexEqual}, "payments", true},
+		{Filter{Term: "^pay.*", Operator: RegexNotEqual}, "payments", false},
+		{Filter{Term: "100", Operator: GreaterThan}, "99", false},
+		{Filter{Term: "100", Operator: GreaterThan}, "152", true},
+		{Filter{Term: "100", Operator: LessThanOrEqual}, "100", true},
+		{Filter{Term: "b", Operator: LessThan}, "a", true},
+		{Filter{Term: "100", Operator: GreaterThan}, "", false},
+		{Filter{Term: "2024-01-02T03:04:05Z", Operator: GreaterThanOrEqual}, "2024-01-02T04:04:05+01:00", true},
+		{Filter{Term: "-15m", Operator: GreaterThan, Attr: timeAttr}, recent, true},
+		{Filter{Term: "-15m", Operator: LessThan, Attr: timeAttr}, recent, false},
+	} {
+		matches, err := test.filter.Matcher()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if matches(test.value) != test.matches {
+			t.Errorf("%s %s %s: expected %v", test.value, test.filter.Operator, test.filter.Term, test.matches)
+		}
+	}
+
+	_, err := Filter{Term: "(", Operator: RegexEqual}.Matcher()
+	AssertEq(t, "invalid regex (: error parsing regexp: missing closing ): `(`", err.Error())
+	_, err = Filter{Term: "last week", Operator: GreaterThan, Attr: timeAttr}.Matcher()
+	AssertEq(t, "invalid time: last week", err.Error())
+}
@@ -0,0 +1,86 @@
+package config
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+)
+
+// Ingest accepts rows pushed over HTTP, so that scripts and test harnesses can add rows to a running
+// session whatever its source is. Each POST to address carries either newline-delimited JSON or a
+// single JSON array, and every JSON value in it becomes a row. A body with invalid JSON is rejected
+// as a whole.
+//
+// Ingest blocks, sending the rows on updates, until ctx is cancelled or it fails to listen.
+func Ingest(ctx context.Context, address string, updates chan<- Update) error {
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		return err
+	}
+	receiver := newRowReceiver()
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", handleIngest(receiver))
+	server := &http.Server{Handler: mux}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		server.Serve(listener)
+	}()
+
+	forwardLines(ctx, receiver.lines, updates)
+	receiver.stop()
+	server.Close()
+	<-done
+	return nil
+}
+
+func handleIngest(receiver *rowReceiver) http.HandlerFunc {
+	parse := func(r *http.Request) ([]Row, error) {
+		data, err := io.ReadAll(r.Body)
+		if err != nil {
+			return nil, err
+		}
+		return ingestRows(data)
+	}
+	reply := func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}
+	return receiver.handlePost(parse, reply)
+}
+
+// ingestRows returns a row for each element of data if it is a JSON array, or else for each
+// non-empty line of data, which must then be a JSON value.
+func ingestRows(data []byte) ([]Row, error) {
+	trimmed := bytes.TrimSpace(data)
+	if bytes.HasPrefix(trimmed, []byte("[")) {
+		var values []json.RawMessage
+		if err := json.Unmarshal(trimmed, &values); err != nil {
+			return nil, err
+		}
+		rows := make([]Row, len(values))
+		for i, value := range values {
+			var compacted bytes.Buffer
+			json.Compact(&compacted, value)
+			rows[i] = Row{Line: compacted.String()}
+		}
+		return rows, nil
+	}
+
+	rows := make([]Row, 0)
+	for i, line := range strings.Split(string(trimmed), "\n") {
+		line = trimEOL(line)
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		if !json.Valid([]byte(line)) {
+			return nil, fmt.Errorf("line %d is not valid JSON", i+1)
+		}
+		rows = append(rows, Row{Line: line})
+	}
+	return rows, nil
+}
@@ -0,0 +1,39 @@
+package config
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	. "github.com/torarvid/gloglog/testutil"
+)
+
+func TestIngest(t *testing.T) {
+	receiver := newRowReceiver()
+	lines := receiver.lines
+	handler := handleIngest(receiver)
+	post := func(body string) int {
+		response := httptest.NewRecorder()
+		handler(response, httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body)))
+		return response.Code
+	}
+
+	AssertEq(t, http.StatusNoContent, post("{\"a\": 1}\r\n\n{\"a\": 2}"))
+	AssertEq(t, `{"a": 1}`, (<-lines).Line)
+	AssertEq(t, `{"a": 2}`, (<-lines).Line)
+
+	AssertEq(t, http.StatusNoContent, post(`[{"a": 3}, {"a": 4}]`))
+	AssertEq(t, `{"a":3}`, (<-lines).Line)
+	AssertEq(t, `{"a":4}`, (<-lines).Line)
+
+	AssertEq(t, http.StatusBadRequest, post("{\"a\": 5}\nnope\n"))
+	AssertEq(t, http.StatusBadRequest, post(`[{"a": 6}`))
+	AssertEq(t, 0, len(lines))
+
+	// Once ingesting has stopped, requests that can't deliver their rows give up
+	receiver = &rowReceiver{lines: make(chan Row), stopped: make(chan struct{})}
+	receiver.stop()
+	handler = handleIngest(receiver)
+	AssertEq(t, http.StatusServiceUnavailable, post(`{"a": 7}`))
+}
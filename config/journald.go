@@ -0,0 +1,292 @@
+package config
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// journalSource reads systemd journal entries as written by `journalctl -o export` or
+// `journalctl -o json`, from the file in Options["filename"], or from standard input if there is no
+// filename or it is "-", as in `journalctl -f -o export | gloglog`.
+//
+// Options["format"] is "export", "json" or empty, in which case the format is recognized from the
+// data. Compressed files are decompressed.
+//
+// Each entry becomes a JSON row with the fields time, severity, priority, unit, hostname,
+// identifier, pid and message, taken from __REALTIME_TIMESTAMP, PRIORITY, _SYSTEMD_UNIT, _HOSTNAME,
+// SYSLOG_IDENTIFIER, _PID and MESSAGE. All of the entry's fields are kept in fields as well.
+type journalSource struct {
+	filename string
+	format   string
+}
+
+// journalStdinSource is a journalSource that reads standard input, which can be followed.
+type journalStdinSource struct {
+	*stdinSource
+}
+
+type journalEntry struct {
+	Time       string         `json:"time,omitempty"`
+	Severity   string         `json:"severity,omitempty"`
+	Priority   *int           `json:"priority,omitempty"`
+	Unit       string         `json:"unit,omitempty"`
+	Hostname   string         `json:"hostname,omitempty"`
+	Identifier string         `json:"identifier,omitempty"`
+	PID        string         `json:"pid,omitempty"`
+	Message    string         `json:"message"`
+	Fields     map[string]any `json:"fields"`
+}
+
+// journalAttrs returns the attributes proposed for a view of the journal that has none yet.
+func journalAttrs() []Attribute {
+	timeFormat := "2006-01-02 15:04:05.000"
+	return []Attribute{
+		{Name: "Time", Width: 23, Selectors: []string{"json(time)"}, Type: "time", Format: &timeFormat},
+		{Name: "Unit", Width: 24, Selectors: []string{"json(unit)", "json(identifier)"}},
+		{Name: "Severity", Width: 8, Selectors: []string{"json(severity)"}},
+		{Name: "Message", Width: 100, Selectors: []string{"json(message)"}},
+	}
+}
+
+func readsJournalStdin(logView LogView) bool {
+	filename := logView.Options["filename"]
+	return filename == "" || filename == "-"
+}
+
+func journalFormat(logView LogView) string {
+	format := logView.Options["format"]
+	if format != "" && format != "export" && format != "json" {
+		panic("Unknown journal format: " + format)
+	}
+	return format
+}
+
+func fromJournal(logView LogView) *journalSource {
+	return &journalSource{filename: logView.Options["filename"], format: journalFormat(logView)}
+}
+
+func fromJournalStdin(logView LogView) *journalStdinSource {
+	format := journalFormat(logView)
+	return &journalStdinSource{newStdinSource(func(reader io.Reader, lines chan<- Row) error {
+		return readJournal(reader, format, lines)
+	})}
+}
+
+func (js *journalSource) Rows() ([]Row, error) {
+	fmt.Fprintf(os.Stderr, "Scanning journal '%s'", js.filename)
+	file, err := os.Open(js.filename)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	reader, _, err := decompress(file)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+
+	lines := make(chan Row, maxBatch)
+	result := make(chan error, 1)
+	go func() {
+		defer close(lines)
+		result <- readJournal(reader, js.format, lines)
+	}()
+	rows := make([]Row, 0)
+	for row := range lines {
+		rows = append(rows, row)
+	}
+	return rows, <-result
+}
+
+func (js *journalSource) SuggestedAttrs() []Attribute {
+	return journalAttrs()
+}
+
+func (jss *journalStdinSource) SuggestedAttrs() []Attribute {
+	return journalAttrs()
+}
+
+// readJournal reads entries in the given format from reader and sends them as rows on lines until
+// EOF. If format is empty, it is recognized from the first byte: JSON entries are objects.
+func readJournal(reader io.Reader, format string, lines chan<- Row) error {
+	buffered := bufio.NewReader(reader)
+	if format == "" {
+		format = "export"
+		for {
+			b, err := buffered.Peek(1)
+			if err != nil {
+				return nil
+			}
+			if b[0] == ' ' || b[0] == '\t' || b[0] == '\r' || b[0] == '\n' {
+				buffered.ReadByte()
+				continue
+			}
+			if b[0] == '{' {
+				format = "json"
+			}
+			break
+		}
+	}
+	if format == "json" {
+		return readJournalJSON(buffered, lines)
+	}
+	return readJournalExport(buffered, lines)
+}
+
+// maxJournalField is the size of the largest binary field value that is read from the export
+// format, which leaves room for big values like core dumps but not for sizes read from corrupt data.
+const maxJournalField = 64 << 20
+
+// readJournalExport reads the journal export format. Each field is either a line KEY=value, or, for
+// values that aren't plain text, the line KEY followed by the value's size as a little-endian
+// 64-bit integer, the value itself and a newline. An empty line ends each entry.
+func readJournalExport(reader *bufio.Reader, lines chan<- Row) error {
+	fields := make(map[string][]string)
+	flush := func() {
+		if len(fields) > 0 {
+			lines <- journalRow(fields)
+			fields = make(map[string][]string)
+		}
+	}
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil && !(errors.Is(err, io.EOF) && line != "") {
+			flush()
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return err
+		}
+		line = strings.TrimSuffix(line, "\n")
+		if line == "" {
+			flush()
+			continue
+		}
+		if key, value, found := strings.Cut(line, "="); found {
+			fields[key] = append(fields[key], value)
+			continue
+		}
+		var size uint64
+		if err := binary.Read(reader, binary.LittleEndian, &size); err != nil {
+			return fmt.Errorf("reading size of journal field %s: %w", line, err)
+		}
+		if size > maxJournalField {
+			return fmt.Errorf("invalid size of journal field %s: %d", line, size)
+		}
+		value := make([]byte, size)
+		if _, err := io.ReadFull(reader, value); err != nil {
+			return fmt.Errorf("reading journal field %s: %w", line, err)
+		}
+		if b, err := reader.ReadByte(); err != nil || b != '\n' {
+			return fmt.Errorf("journal field %s is not terminated by a newline", line)
+		}
+		fields[line] = append(fields[line], string(value))
+	}
+}
+
+// readJournalJSON reads the JSON format of the journal, one object per entry. A value is a string,
+// an array of bytes if it isn't plain text, or an array of such values if the field occurs more
+// than once in the entry.
+func readJournalJSON(reader io.Reader, lines chan<- Row) error {
+	decoder := json.NewDecoder(reader)
+	for {
+		var entry map[string]any
+		err := decoder.Decode(&entry)
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		fields := make(map[string][]string, len(entry))
+		for key, value := range entry {
+			values, ok := value.([]any)
+			if !ok || isJournalBytes(values) {
+				values = []any{value}
+			}
+			for _, v := range values {
+				if s, ok := journalJSONValue(v); ok {
+					fields[key] = append(fields[key], s)
+				}
+			}
+		}
+		lines <- journalRow(fields)
+	}
+}
+
+func isJournalBytes(values []any) bool {
+	for _, v := range values {
+		if _, ok := v.(float64); !ok {
+			return false
+		}
+	}
+	return len(values) > 0
+}
+
+func journalJSONValue(value any) (string, bool) {
+	switch v := value.(type) {
+	case string:
+		return v, true
+	case []any:
+		data := make([]byte, 0, len(v))
+		for _, b := range v {
+			n, ok := b.(float64)
+			if !ok {
+				return "", false
+			}
+			data = append(data, byte(n))
+		}
+		return string(data), true
+	default:
+		return "", false
+	}
+}
+
+// journalRow turns the fields of a journal entry into a row.
+func journalRow(fields map[string][]string) Row {
+	first := func(key string) string {
+		if values := fields[key]; len(values) > 0 {
+			return values[0]
+		}
+		return ""
+	}
+	entry := journalEntry{
+		Unit:       first("_SYSTEMD_UNIT"),
+		Hostname:   first("_HOSTNAME"),
+		Identifier: first("SYSLOG_IDENTIFIER"),
+		PID:        first("_PID"),
+		Message:    strings.TrimRight(first("MESSAGE"), "\n"),
+		Fields:     make(map[string]any, len(fields)),
+	}
+	if entry.Unit == "" {
+		entry.Unit = first("_SYSTEMD_USER_UNIT")
+	}
+	if usec, err := strconv.ParseInt(first("__REALTIME_TIMESTAMP"), 10, 64); err == nil {
+		entry.Time = time.UnixMicro(usec).UTC().Format(time.RFC3339Nano)
+	}
+	if priority, err := strconv.Atoi(first("PRIORITY")); err == nil && priority >= 0 && priority < 8 {
+		entry.Priority = &priority
+		entry.Severity = syslogSeverities[priority]
+	}
+	for key, values := range fields {
+		if len(values) == 1 {
+			entry.Fields[key] = values[0]
+		} else {
+			entry.Fields[key] = values
+		}
+	}
+	var line bytes.Buffer
+	encoder := json.NewEncoder(&line)
+	encoder.SetEscapeHTML(false)
+	encoder.Encode(entry)
+	return Row{Line: strings.TrimSuffix(line.String(), "\n")}
+}
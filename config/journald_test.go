@@ -0,0 +1,66 @@
+package config
+
+import (
+	"encoding/binary"
+	"strings"
+	"testing"
+
+	"github.com/tidwall/gjson"
+	. "github.com/torarvid/gloglog/testutil"
+)
+
+func readJournalRows(t *testing.T, data, format string) []Row {
+	lines := make(chan Row, 10)
+	if err := readJournal(strings.NewReader(data), format, lines); err != nil {
+		t.Fatal(err)
+	}
+	close(lines)
+	rows := make([]Row, 0)
+	for row := range lines {
+		rows = append(rows, row)
+	}
+	return rows
+}
+
+func TestJournalExport(t *testing.T) {
+	message := "multi\nline"
+	size := make([]byte, 8)
+	binary.LittleEndian.PutUint64(size, uint64(len(message)))
+	export := "__REALTIME_TIMESTAMP=1700000000123456\nPRIORITY=3\n_SYSTEMD_UNIT=nginx.service\n" +
+		"MESSAGE\n" + string(size) + message + "\n\n" +
+		"__REALTIME_TIMESTAMP=1700000001000000\nMESSAGE=second\nTAG=a\nTAG=b\n"
+
+	rows := readJournalRows(t, export, "")
+	AssertEq(t, 2, len(rows))
+	AssertEq(t, "2023-11-14T22:13:20.123456Z", gjson.Get(rows[0].Line, "time").String())
+	AssertEq(t, "err", gjson.Get(rows[0].Line, "severity").String())
+	AssertEq(t, int64(3), gjson.Get(rows[0].Line, "priority").Int())
+	AssertEq(t, "nginx.service", gjson.Get(rows[0].Line, "unit").String())
+	AssertEq(t, message, gjson.Get(rows[0].Line, "message").String())
+	AssertEq(t, "second", gjson.Get(rows[1].Line, "message").String())
+	AssertEq(t, false, gjson.Get(rows[1].Line, "priority").Exists())
+	AssertEq(t, "b", gjson.Get(rows[1].Line, "fields.TAG.1").String())
+}
+
+func TestJournalExportCorruptSize(t *testing.T) {
+	size := make([]byte, 8)
+	binary.LittleEndian.PutUint64(size, 1<<62)
+	lines := make(chan Row, 10)
+	err := readJournal(strings.NewReader("MESSAGE\n"+string(size)+"hi\n\n"), "export", lines)
+	if err == nil || !strings.Contains(err.Error(), "invalid size of journal field MESSAGE") {
+		t.Errorf("Expected a corrupt size to be rejected, got %v", err)
+	}
+}
+
+func TestJournalJSON(t *testing.T) {
+	json := `{"__REALTIME_TIMESTAMP":"1700000000123456","PRIORITY":"6","_SYSTEMD_UNIT":"sshd.service",` +
+		`"MESSAGE":[104,105,10]}` + "\n" + `{"MESSAGE":"plain","_PID":["1","2"]}` + "\n"
+
+	rows := readJournalRows(t, json, "")
+	AssertEq(t, 2, len(rows))
+	AssertEq(t, "info", gjson.Get(rows[0].Line, "severity").String())
+	AssertEq(t, "sshd.service", gjson.Get(rows[0].Line, "unit").String())
+	AssertEq(t, "hi", gjson.Get(rows[0].Line, "message").String())
+	AssertEq(t, "plain", gjson.Get(rows[1].Line, "message").String())
+	AssertEq(t, "1", gjson.Get(rows[1].Line, "pid").String())
+}
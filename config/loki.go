@@ -0,0 +1,259 @@
+package config
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// lokiPageSize is the most entries asked for in one query_range request.
+var lokiPageSize = 1000
+
+// lokiSource reads rows from Grafana Loki through its HTTP API.
+//
+// Options:
+//   - url: where Loki is, e.g. http://localhost:3100
+//   - query: the LogQL query
+//   - start, end: the time range; RFC3339 times or durations relative to now (default -1h and now)
+//   - limit: the most rows to load (default 5000)
+//   - org_id: the tenant, sent as X-Scope-OrgID
+//
+// Rows are loaded oldest first by paging through query_range. Following tails the query over the
+// websocket endpoint, picking up after the newest row loaded. Each row's Origin is the labels of
+// the stream it belongs to.
+type lokiSource struct {
+	url    string
+	query  string
+	start  time.Time
+	end    time.Time
+	limit  int
+	orgID  string
+	client *http.Client
+
+	mu sync.Mutex
+	// last is the timestamp, in Unix nanoseconds, of the newest entry read so far, and seen the
+	// entries read with that timestamp. Reading continues from last, as there may be more entries
+	// with the same timestamp, and the ones in seen are skipped.
+	last int64
+	seen map[lokiEntry]bool
+}
+
+type lokiStream struct {
+	Stream map[string]string `json:"stream"`
+	Values [][2]string       `json:"values"`
+}
+
+type lokiQueryResponse struct {
+	Status string `json:"status"`
+	Data   struct {
+		ResultType string       `json:"resultType"`
+		Result     []lokiStream `json:"result"`
+	} `json:"data"`
+}
+
+type lokiTailResponse struct {
+	Streams []lokiStream `json:"streams"`
+}
+
+type lokiEntry struct {
+	ts     int64
+	line   string
+	labels string
+}
+
+func fromLoki(logView LogView) *lokiSource {
+	lokiURL, exists := logView.Options["url"]
+	if !exists {
+		panic("url not found")
+	}
+	query, exists := logView.Options["query"]
+	if !exists {
+		panic("query not found")
+	}
+	limit := 5000
+	if value, exists := logView.Options["limit"]; exists {
+		var err error
+		if limit, err = strconv.Atoi(value); err != nil {
+			panic(err)
+		}
+	}
+	now := time.Now()
+	return &lokiSource{
+		url:    strings.TrimSuffix(lokiURL, "/"),
+		query:  query,
+		start:  logView.timeOption("start", "-1h", now),
+		end:    logView.timeOption("end", "now", now),
+		limit:  limit,
+		orgID:  logView.Options["org_id"],
+		client: http.DefaultClient,
+	}
+}
+
+func (ls *lokiSource) Rows() ([]Row, error) {
+	fmt.Fprintf(os.Stderr, "Querying Loki at '%s'", ls.url)
+	rows := make([]Row, 0)
+	start := ls.start.UnixNano()
+	ls.last, ls.seen = 0, nil
+	for len(rows) < ls.limit {
+		// The entries already seen at the start of the page come again, so ask for that many more
+		pageSize := min(lokiPageSize, ls.limit-len(rows)) + len(ls.seen)
+		entries, err := ls.queryRange(start, pageSize)
+		if err != nil {
+			return nil, err
+		}
+		unseen := ls.unseen(entries)
+		for _, entry := range unseen[:min(len(unseen), ls.limit-len(rows))] {
+			rows = append(rows, Row{Line: entry.line, Origin: entry.labels})
+		}
+		if len(entries) < pageSize || len(unseen) == 0 {
+			break
+		}
+		start = ls.last
+	}
+	return rows, nil
+}
+
+// unseen returns the entries that haven't been read before, and moves last past them. The entries
+// must be ordered by time.
+func (ls *lokiSource) unseen(entries []lokiEntry) []lokiEntry {
+	unseen := make([]lokiEntry, 0, len(entries))
+	for _, entry := range entries {
+		if entry.ts < ls.last || (entry.ts == ls.last && ls.seen[entry]) {
+			continue
+		}
+		if entry.ts > ls.last || ls.seen == nil {
+			ls.last, ls.seen = entry.ts, make(map[lokiEntry]bool)
+		}
+		ls.seen[entry] = true
+		unseen = append(unseen, entry)
+	}
+	return unseen
+}
+
+// queryRange fetches up to limit entries from start until the end of the time range, oldest first.
+func (ls *lokiSource) queryRange(start int64, limit int) ([]lokiEntry, error) {
+	params := url.Values{}
+	params.Set("query", ls.query)
+	params.Set("start", strconv.FormatInt(start, 10))
+	params.Set("end", strconv.FormatInt(ls.end.UnixNano(), 10))
+	params.Set("limit", strconv.Itoa(limit))
+	params.Set("direction", "forward")
+	req, err := http.NewRequest("GET", ls.url+"/loki/api/v1/query_range?"+params.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+	ls.setHeaders(req.Header)
+	resp, err := ls.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("loki query failed: %s", resp.Status)
+	}
+
+	var result lokiQueryResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, err
+	}
+	if result.Data.ResultType != "streams" {
+		return nil, fmt.Errorf("loki query returned %s, not log streams", result.Data.ResultType)
+	}
+	return lokiEntries(result.Data.Result)
+}
+
+// Follow tails the query, starting with the entries after the newest one loaded by Rows.
+func (ls *lokiSource) Follow(ctx context.Context, updates chan<- Update) error {
+	ls.mu.Lock()
+	defer ls.mu.Unlock()
+
+	params := url.Values{}
+	params.Set("query", ls.query)
+	params.Set("start", strconv.FormatInt(max(ls.last, ls.start.UnixNano()), 10))
+	tailURL := ls.url + "/loki/api/v1/tail?" + params.Encode()
+	tailURL = "ws" + strings.TrimPrefix(tailURL, "http")
+
+	header := http.Header{}
+	ls.setHeaders(header)
+	conn, _, err := websocket.DefaultDialer.DialContext(ctx, tailURL, header)
+	if err != nil {
+		return err
+	}
+	go func() {
+		<-ctx.Done()
+		conn.Close()
+	}()
+	defer conn.Close()
+
+	for {
+		var result lokiTailResponse
+		if err := conn.ReadJSON(&result); err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+		entries, err := lokiEntries(result.Streams)
+		if err != nil {
+			return err
+		}
+		entries = ls.unseen(entries)
+		if len(entries) == 0 {
+			continue
+		}
+		rows := make([]Row, len(entries))
+		for i, entry := range entries {
+			rows[i] = Row{Line: entry.line, Origin: entry.labels}
+		}
+		if !send(ctx, updates, Update{Rows: rows}) {
+			return nil
+		}
+	}
+}
+
+func (ls *lokiSource) setHeaders(header http.Header) {
+	if ls.orgID != "" {
+		header.Set("X-Scope-OrgID", ls.orgID)
+	}
+}
+
+// lokiEntries flattens the entries of several streams into one list ordered by time.
+func lokiEntries(streams []lokiStream) ([]lokiEntry, error) {
+	entries := make([]lokiEntry, 0)
+	for _, stream := range streams {
+		labels := lokiLabels(stream.Stream)
+		for _, value := range stream.Values {
+			ts, err := strconv.ParseInt(value[0], 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid loki timestamp: %s", value[0])
+			}
+			entries = append(entries, lokiEntry{ts: ts, line: value[1], labels: labels})
+		}
+	}
+	sort.SliceStable(entries, func(i, j int) bool { return entries[i].ts < entries[j].ts })
+	return entries, nil
+}
+
+// lokiLabels formats a label set the way LogQL does, e.g. {app="api", env="prod"}.
+func lokiLabels(labels map[string]string) string {
+	names := make([]string, 0, len(labels))
+	for name := range labels {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	pairs := make([]string, len(names))
+	for i, name := range names {
+		pairs[i] = fmt.Sprintf("%s=%q", name, labels[name])
+	}
+	return "{" + strings.Join(pairs, ", ") + "}"
+}
@@ -0,0 +1,83 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/websocket"
+	. "github.com/torarvid/gloglog/testutil"
+)
+
+// fakeLoki serves n entries from two streams, two entries per timestamp, and tails one more entry
+// along with the last one again.
+func fakeLoki(t *testing.T, n int) *httptest.Server {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/loki/api/v1/query_range", func(w http.ResponseWriter, r *http.Request) {
+		AssertEq(t, `{app="api"}`, r.URL.Query().Get("query"))
+		AssertEq(t, "tenant", r.Header.Get("X-Scope-OrgID"))
+		start, _ := strconv.Atoi(r.URL.Query().Get("start"))
+		limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+		values := map[string][]string{"a": {}, "b": {}}
+		for i := max(2*start-1, 1); i <= n && i < max(2*start-1, 1)+limit; i++ {
+			stream := "a"
+			if i%2 == 0 {
+				stream = "b"
+			}
+			values[stream] = append(values[stream], fmt.Sprintf(`["%d", "line %d"]`, (i+1)/2, i))
+		}
+		fmt.Fprintf(w, `{"status": "success", "data": {"resultType": "streams", "result": [`)
+		fmt.Fprintf(w, `{"stream": {"app": "api", "pod": "a"}, "values": [%s]},`, strings.Join(values["a"], ","))
+		fmt.Fprintf(w, `{"stream": {"app": "api", "pod": "b"}, "values": [%s]}`, strings.Join(values["b"], ","))
+		fmt.Fprintf(w, `]}}`)
+	})
+	mux.HandleFunc("/loki/api/v1/tail", func(w http.ResponseWriter, r *http.Request) {
+		AssertEq(t, strconv.Itoa((n+1)/2), r.URL.Query().Get("start"))
+		conn, err := (&websocket.Upgrader{}).Upgrade(w, r, nil)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		defer conn.Close()
+		conn.WriteMessage(websocket.TextMessage, []byte(fmt.Sprintf(
+			`{"streams": [{"stream": {"app": "api", "pod": "b"}, "values": [%s, ["%d", "tailed"]]}]}`,
+			fmt.Sprintf(`["%d", "line %d"]`, (n+1)/2, n), (n+1)/2,
+		)))
+		conn.ReadMessage()
+	})
+	return httptest.NewServer(mux)
+}
+
+func TestLokiSource(t *testing.T) {
+	lokiPageSize = 3
+	server := fakeLoki(t, 10)
+	defer server.Close()
+
+	source := fromLoki(LogView{Options: map[string]string{
+		"url":    server.URL,
+		"query":  `{app="api"}`,
+		"start":  "1970-01-01T00:00:00Z",
+		"org_id": "tenant",
+	}})
+	rows, err := source.Rows()
+	if err != nil {
+		t.Fatal(err)
+	}
+	AssertEq(t, 10, len(rows))
+	for i, row := range rows {
+		AssertEq(t, fmt.Sprintf("line %d", i+1), row.Line)
+	}
+	AssertEq(t, `{app="api", pod="b"}`, rows[1].Origin)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	updates := make(chan Update)
+	go source.Follow(ctx, updates)
+	update := <-updates
+	AssertEq(t, 1, len(update.Rows))
+	AssertEq(t, "tailed", update.Rows[0].Line)
+}
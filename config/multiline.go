@@ -0,0 +1,175 @@
+package config
+
+import (
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// multilineRows groups consecutive lines that make up one record, like a stack trace or a
+// pretty-printed JSON object, into a single row whose Line holds all of them, separated by
+// newlines. It is used for views with one of these options:
+//   - multiline_start: a regular expression matching the first line of each record. Other lines
+//     belong to the record before them.
+//   - multiline_continue: a regular expression matching lines that belong to the record before
+//     them, like "^\s" for indented stack frames. Other lines start a new record.
+//   - multiline: "json", where a line that opens a JSON object or array starts a record that lasts
+//     until the brackets are balanced.
+//
+// Lines from different origins are grouped separately.
+type multilineRows struct {
+	startPattern    *regexp.Regexp
+	continuePattern *regexp.Regexp
+	json            bool
+
+	mu      sync.Mutex
+	pending []*pendingRecord
+}
+
+type pendingRecord struct {
+	origin string
+	lines  []string
+	// depth is how many brackets are open, for JSON records.
+	depth    int
+	inString bool
+	escaped  bool
+}
+
+func withMultiline(logView LogView, source Source) Source {
+	ms := &multilineRows{}
+	switch {
+	case logView.Options["multiline"] == "json":
+		ms.json = true
+	case logView.Options["multiline"] != "":
+		panic("Unknown multiline rule: " + logView.Options["multiline"])
+	case logView.Options["multiline_start"] != "":
+		ms.startPattern = regexp.MustCompile(logView.Options["multiline_start"])
+	case logView.Options["multiline_continue"] != "":
+		ms.continuePattern = regexp.MustCompile(logView.Options["multiline_continue"])
+	default:
+		return source
+	}
+	return decorate(source, ms)
+}
+
+// decorate returns the records in rows that are complete, holding back the others until more lines
+// arrive or they are flushed.
+func (ms *multilineRows) decorate(rows []Row) ([]Row, bool) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	assembled := ms.assemble(rows)
+	return assembled, len(ms.pending) > 0
+}
+
+func (ms *multilineRows) flush() []Row {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	return ms.flushPending()
+}
+
+// assemble groups lines in rows into records. It returns the records that are complete, keeping
+// the others pending until more lines arrive or they are flushed. ms.mu must be held.
+func (ms *multilineRows) assemble(rows []Row) []Row {
+	assembled := make([]Row, 0, len(rows))
+	for _, row := range rows {
+		if row.Marker {
+			assembled = append(append(assembled, ms.flushPending()...), row)
+			continue
+		}
+		record := ms.pendingFor(row.Origin)
+		switch {
+		case ms.json:
+			if record == nil {
+				trimmed := strings.TrimSpace(row.Line)
+				if !strings.HasPrefix(trimmed, "{") && !strings.HasPrefix(trimmed, "[") {
+					assembled = append(assembled, row)
+					continue
+				}
+				record = ms.startRecord(row.Origin)
+			}
+			record.lines = append(record.lines, row.Line)
+			record.scanJSON(row.Line)
+			if record.depth <= 0 {
+				assembled = append(assembled, ms.complete(record))
+			}
+		case ms.startPattern != nil:
+			if record != nil && ms.startPattern.MatchString(row.Line) {
+				assembled = append(assembled, ms.complete(record))
+				record = nil
+			}
+			if record == nil {
+				record = ms.startRecord(row.Origin)
+			}
+			record.lines = append(record.lines, row.Line)
+		default:
+			if record != nil && !ms.continuePattern.MatchString(row.Line) {
+				assembled = append(assembled, ms.complete(record))
+				record = nil
+			}
+			if record == nil {
+				record = ms.startRecord(row.Origin)
+			}
+			record.lines = append(record.lines, row.Line)
+		}
+	}
+	return assembled
+}
+
+// flushPending returns all pending records as rows. ms.mu must be held.
+func (ms *multilineRows) flushPending() []Row {
+	rows := make([]Row, len(ms.pending))
+	for i, record := range ms.pending {
+		rows[i] = Row{Line: strings.Join(record.lines, "\n"), Origin: record.origin}
+	}
+	ms.pending = nil
+	return rows
+}
+
+func (ms *multilineRows) pendingFor(origin string) *pendingRecord {
+	for _, record := range ms.pending {
+		if record.origin == origin {
+			return record
+		}
+	}
+	return nil
+}
+
+func (ms *multilineRows) startRecord(origin string) *pendingRecord {
+	record := &pendingRecord{origin: origin}
+	ms.pending = append(ms.pending, record)
+	return record
+}
+
+// complete removes record from the pending records and returns it as a row.
+func (ms *multilineRows) complete(record *pendingRecord) Row {
+	for i, pending := range ms.pending {
+		if pending == record {
+			ms.pending = append(ms.pending[:i], ms.pending[i+1:]...)
+			break
+		}
+	}
+	return Row{Line: strings.Join(record.lines, "\n"), Origin: record.origin}
+}
+
+// scanJSON keeps track of how many brackets are open after line, ignoring those in strings.
+func (record *pendingRecord) scanJSON(line string) {
+	for i := 0; i < len(line); i++ {
+		c := line[i]
+		switch {
+		case record.escaped:
+			record.escaped = false
+		case record.inString:
+			if c == '\\' {
+				record.escaped = true
+			} else if c == '"' {
+				record.inString = false
+			}
+		case c == '"':
+			record.inString = true
+		case c == '{' || c == '[':
+			record.depth++
+		case c == '}' || c == ']':
+			record.depth--
+		}
+	}
+}
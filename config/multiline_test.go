@@ -0,0 +1,74 @@
+package config
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	. "github.com/torarvid/gloglog/testutil"
+)
+
+func TestMultiline(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "app.log")
+	data := "2024-01-02 INFO starting\n" +
+		"2024-01-02 ERROR boom\n" +
+		"java.lang.RuntimeException: boom\n" +
+		"\tat Main.main(Main.java:1)\n" +
+		"2024-01-02 INFO done\n"
+	if err := os.WriteFile(filename, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, options := range []map[string]string{
+		{"filename": filename, "multiline_start": `^\d{4}-\d{2}-\d{2} `},
+		{"filename": filename, "multiline_continue": `^(\s|java\.)`},
+	} {
+		rows, err := LogView{SourceId: "file", Options: options}.GetSource().Rows()
+		if err != nil {
+			t.Fatal(err)
+		}
+		AssertEq(t, 3, len(rows))
+		AssertEq(t, "2024-01-02 ERROR boom\njava.lang.RuntimeException: boom\n\tat Main.main(Main.java:1)", rows[1].Line)
+		AssertEq(t, "2024-01-02 INFO done", rows[2].Line)
+	}
+
+	json := "plain\n" + `{"a": "}{",` + "\n" + ` "b": [1,` + "\n" + ` 2]}` + "\n" + `{"c": 3}` + "\n"
+	if err := os.WriteFile(filename, []byte(json), 0644); err != nil {
+		t.Fatal(err)
+	}
+	options := map[string]string{"filename": filename, "multiline": "json"}
+	rows, err := LogView{SourceId: "file", Options: options}.GetSource().Rows()
+	if err != nil {
+		t.Fatal(err)
+	}
+	AssertEq(t, 3, len(rows))
+	AssertEq(t, "plain", rows[0].Line)
+	AssertEq(t, `{"a": "}{",`+"\n"+` "b": [1,`+"\n"+` 2]}`, rows[1].Line)
+	AssertEq(t, `{"c": 3}`, rows[2].Line)
+}
+
+func TestMultilineFollow(t *testing.T) {
+	followInterval = 10 * time.Millisecond
+	flushDelay = 50 * time.Millisecond
+	filename := filepath.Join(t.TempDir(), "app.log")
+	appendTo(t, filename, "")
+
+	options := map[string]string{"filename": filename, "multiline_continue": `^\s`}
+	source := LogView{SourceId: "file", Options: options}.GetSource()
+	if _, err := source.Rows(); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	updates := make(chan Update)
+	go source.(Follower).Follow(ctx, updates)
+
+	appendTo(t, filename, "panic: oops\n")
+	time.Sleep(20 * time.Millisecond)
+	appendTo(t, filename, "  goroutine 1\nnext\n")
+	rows := collectRows(updates, 2)
+	AssertEq(t, "panic: oops\n  goroutine 1", rows[0].Line)
+	AssertEq(t, "next", rows[1].Line)
+}
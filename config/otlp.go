@@ -0,0 +1,367 @@
+package config
+
+import (
+	"compress/gzip"
+	"context"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	collogs "go.opentelemetry.io/proto/otlp/collector/logs/v1"
+	common "go.opentelemetry.io/proto/otlp/common/v1"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+// otlpSource receives OpenTelemetry logs, acting as a local OTLP endpoint that services can export
+// their logs to.
+//
+// Options:
+//   - protocol: http, grpc or both (the default)
+//   - http_address: where to listen for OTLP/HTTP, protobuf or JSON (default localhost:4318)
+//   - grpc_address: where to listen for OTLP/gRPC (default localhost:4317)
+//
+// Each LogRecord becomes a JSON row with the fields time, observed_time, severity,
+// severity_number, body, attributes, resource (the resource attributes), scope (name, version and
+// attributes), trace_id, span_id and flags. A row's Origin is the service.name of its resource.
+type otlpSource struct {
+	protocol    string
+	httpAddress string
+	grpcAddress string
+
+	mu sync.Mutex
+}
+
+type otlpRecord struct {
+	Time           string         `json:"time,omitempty"`
+	ObservedTime   string         `json:"observed_time,omitempty"`
+	Severity       string         `json:"severity,omitempty"`
+	SeverityNumber int32          `json:"severity_number,omitempty"`
+	Body           any            `json:"body"`
+	Attributes     map[string]any `json:"attributes,omitempty"`
+	Resource       map[string]any `json:"resource,omitempty"`
+	Scope          *otlpScope     `json:"scope,omitempty"`
+	TraceID        string         `json:"trace_id,omitempty"`
+	SpanID         string         `json:"span_id,omitempty"`
+	Flags          uint32         `json:"flags,omitempty"`
+}
+
+type otlpScope struct {
+	Name       string         `json:"name,omitempty"`
+	Version    string         `json:"version,omitempty"`
+	Attributes map[string]any `json:"attributes,omitempty"`
+}
+
+func fromOTLP(logView LogView) *otlpSource {
+	src := &otlpSource{
+		protocol:    logView.Options["protocol"],
+		httpAddress: logView.Options["http_address"],
+		grpcAddress: logView.Options["grpc_address"],
+	}
+	if src.protocol == "" {
+		src.protocol = "both"
+	}
+	if src.protocol != "http" && src.protocol != "grpc" && src.protocol != "both" {
+		panic("Unknown protocol: " + src.protocol)
+	}
+	if src.httpAddress == "" {
+		src.httpAddress = "localhost:4318"
+	}
+	if src.grpcAddress == "" {
+		src.grpcAddress = "localhost:4317"
+	}
+	return src
+}
+
+func (src *otlpSource) listening() string {
+	addresses := make([]string, 0, 2)
+	if src.protocol != "grpc" {
+		addresses = append(addresses, "http://"+src.httpAddress+"/v1/logs")
+	}
+	if src.protocol != "http" {
+		addresses = append(addresses, "grpc://"+src.grpcAddress)
+	}
+	return "Receiving OTLP logs on " + strings.Join(addresses, " and ")
+}
+
+func (src *otlpSource) Rows() ([]Row, error) {
+	fmt.Fprint(os.Stderr, src.listening())
+	return []Row{}, nil
+}
+
+func (src *otlpSource) Follow(ctx context.Context, updates chan<- Update) error {
+	src.mu.Lock()
+	defer src.mu.Unlock()
+
+	receiver := newRowReceiver()
+	var wg sync.WaitGroup
+	stops := make([]func(), 0, 2)
+	stopAll := func() {
+		receiver.stop()
+		for _, stop := range stops {
+			stop()
+		}
+		wg.Wait()
+	}
+
+	if src.protocol != "grpc" {
+		listener, err := net.Listen("tcp", src.httpAddress)
+		if err != nil {
+			return err
+		}
+		mux := http.NewServeMux()
+		mux.HandleFunc("/v1/logs", handleOTLPHTTP(receiver))
+		server := &http.Server{Handler: mux}
+		stops = append(stops, func() { server.Close() })
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			server.Serve(listener)
+		}()
+	}
+	if src.protocol != "http" {
+		listener, err := net.Listen("tcp", src.grpcAddress)
+		if err != nil {
+			stopAll()
+			return err
+		}
+		server := grpc.NewServer()
+		collogs.RegisterLogsServiceServer(server, &otlpLogsService{receiver: receiver})
+		stops = append(stops, server.Stop)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			server.Serve(listener)
+		}()
+	}
+
+	if send(ctx, updates, Update{Status: src.listening()}) {
+		forwardLines(ctx, receiver.lines, updates)
+	}
+	stopAll()
+	return nil
+}
+
+type otlpLogsService struct {
+	collogs.UnimplementedLogsServiceServer
+	receiver *rowReceiver
+}
+
+func (s *otlpLogsService) Export(
+	ctx context.Context,
+	req *collogs.ExportLogsServiceRequest,
+) (*collogs.ExportLogsServiceResponse, error) {
+	if err := s.receiver.receive(ctx, otlpRows(req)); err != nil {
+		if errors.Is(err, errStopped) {
+			return nil, status.Error(codes.Unavailable, err.Error())
+		}
+		return nil, err
+	}
+	return &collogs.ExportLogsServiceResponse{}, nil
+}
+
+// handleOTLPHTTP handles OTLP/HTTP export requests, encoded as either protobuf or JSON.
+func handleOTLPHTTP(receiver *rowReceiver) http.HandlerFunc {
+	parse := func(r *http.Request) ([]Row, error) {
+		var body io.Reader = r.Body
+		if r.Header.Get("Content-Encoding") == "gzip" {
+			gz, err := gzip.NewReader(r.Body)
+			if err != nil {
+				return nil, err
+			}
+			body = gz
+		}
+		data, err := io.ReadAll(body)
+		if err != nil {
+			return nil, err
+		}
+
+		req := &collogs.ExportLogsServiceRequest{}
+		switch otlpContentType(r) {
+		case "application/json":
+			err = unmarshalOTLPJSON(data, req)
+		case "application/x-protobuf":
+			err = proto.Unmarshal(data, req)
+		default:
+			return nil, httpError{http.StatusUnsupportedMediaType, "unsupported content type"}
+		}
+		if err != nil {
+			return nil, err
+		}
+		return otlpRows(req), nil
+	}
+	reply := func(w http.ResponseWriter, r *http.Request) {
+		contentType := otlpContentType(r)
+		w.Header().Set("Content-Type", contentType)
+		if contentType == "application/json" {
+			w.Write([]byte("{}"))
+		} else {
+			response, _ := proto.Marshal(&collogs.ExportLogsServiceResponse{})
+			w.Write(response)
+		}
+	}
+	return receiver.handlePost(parse, reply)
+}
+
+func otlpContentType(r *http.Request) string {
+	contentType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	return contentType
+}
+
+// unmarshalOTLPJSON decodes an OTLP/JSON request. OTLP/JSON differs from the standard JSON mapping of
+// protobuf in that trace and span ids are hex rather than base64 encoded, so those are converted
+// before decoding.
+func unmarshalOTLPJSON(data []byte, req *collogs.ExportLogsServiceRequest) error {
+	var raw map[string]any
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	for _, resourceLogs := range jsonArray(raw, "resourceLogs", "resource_logs") {
+		for _, scopeLogs := range jsonArray(resourceLogs, "scopeLogs", "scope_logs") {
+			for _, record := range jsonArray(scopeLogs, "logRecords", "log_records") {
+				for _, key := range []string{"traceId", "trace_id", "spanId", "span_id"} {
+					id, ok := record[key].(string)
+					if !ok {
+						continue
+					}
+					decoded, err := hex.DecodeString(id)
+					if err != nil {
+						return fmt.Errorf("invalid %s: %s", key, id)
+					}
+					record[key] = base64.StdEncoding.EncodeToString(decoded)
+				}
+			}
+		}
+	}
+	converted, err := json.Marshal(raw)
+	if err != nil {
+		return err
+	}
+	return protojson.UnmarshalOptions{DiscardUnknown: true}.Unmarshal(converted, req)
+}
+
+// jsonArray returns the objects in the array found under the first of keys that obj has.
+func jsonArray(obj map[string]any, keys ...string) []map[string]any {
+	for _, key := range keys {
+		items, ok := obj[key].([]any)
+		if !ok {
+			continue
+		}
+		objects := make([]map[string]any, 0, len(items))
+		for _, item := range items {
+			if object, ok := item.(map[string]any); ok {
+				objects = append(objects, object)
+			}
+		}
+		return objects
+	}
+	return nil
+}
+
+// otlpRows flattens every LogRecord in req, along with its resource and scope, into a row.
+func otlpRows(req *collogs.ExportLogsServiceRequest) []Row {
+	rows := make([]Row, 0)
+	for _, resourceLogs := range req.GetResourceLogs() {
+		resource := otlpAttributes(resourceLogs.GetResource().GetAttributes())
+		origin, _ := resource["service.name"].(string)
+		for _, scopeLogs := range resourceLogs.GetScopeLogs() {
+			var scope *otlpScope
+			if s := scopeLogs.GetScope(); s != nil {
+				scope = &otlpScope{
+					Name:       s.GetName(),
+					Version:    s.GetVersion(),
+					Attributes: otlpAttributes(s.GetAttributes()),
+				}
+			}
+			for _, record := range scopeLogs.GetLogRecords() {
+				severity := record.GetSeverityText()
+				if severity == "" && record.GetSeverityNumber() != 0 {
+					severity = strings.ToLower(
+						strings.TrimPrefix(record.GetSeverityNumber().String(), "SEVERITY_NUMBER_"),
+					)
+				}
+				flat := otlpRecord{
+					Time:           otlpTime(record.GetTimeUnixNano()),
+					ObservedTime:   otlpTime(record.GetObservedTimeUnixNano()),
+					Severity:       severity,
+					SeverityNumber: int32(record.GetSeverityNumber()),
+					Body:           otlpValue(record.GetBody()),
+					Attributes:     otlpAttributes(record.GetAttributes()),
+					Resource:       resource,
+					Scope:          scope,
+					TraceID:        hex.EncodeToString(record.GetTraceId()),
+					SpanID:         hex.EncodeToString(record.GetSpanId()),
+					Flags:          record.GetFlags(),
+				}
+				line, err := json.Marshal(flat)
+				if err != nil {
+					continue
+				}
+				rows = append(rows, Row{Line: string(line), Origin: origin})
+			}
+		}
+	}
+	return rows
+}
+
+func otlpTime(unixNano uint64) string {
+	if unixNano == 0 {
+		return ""
+	}
+	return time.Unix(0, int64(unixNano)).UTC().Format(time.RFC3339Nano)
+}
+
+func otlpAttributes(attributes []*common.KeyValue) map[string]any {
+	if len(attributes) == 0 {
+		return nil
+	}
+	values := make(map[string]any, len(attributes))
+	for _, kv := range attributes {
+		values[kv.GetKey()] = otlpValue(kv.GetValue())
+	}
+	return values
+}
+
+// otlpValue converts an AnyValue to the plain value it holds, with arrays and key-value lists
+// converted recursively.
+func otlpValue(value *common.AnyValue) any {
+	switch v := value.GetValue().(type) {
+	case *common.AnyValue_StringValue:
+		return v.StringValue
+	case *common.AnyValue_BoolValue:
+		return v.BoolValue
+	case *common.AnyValue_IntValue:
+		return v.IntValue
+	case *common.AnyValue_DoubleValue:
+		return v.DoubleValue
+	case *common.AnyValue_BytesValue:
+		return v.BytesValue
+	case *common.AnyValue_ArrayValue:
+		values := make([]any, len(v.ArrayValue.GetValues()))
+		for i, item := range v.ArrayValue.GetValues() {
+			values[i] = otlpValue(item)
+		}
+		return values
+	case *common.AnyValue_KvlistValue:
+		values := otlpAttributes(v.KvlistValue.GetValues())
+		if values == nil {
+			values = map[string]any{}
+		}
+		return values
+	default:
+		return nil
+	}
+}
@@ -0,0 +1,95 @@
+package config
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/tidwall/gjson"
+	. "github.com/torarvid/gloglog/testutil"
+	collogs "go.opentelemetry.io/proto/otlp/collector/logs/v1"
+	common "go.opentelemetry.io/proto/otlp/common/v1"
+	logs "go.opentelemetry.io/proto/otlp/logs/v1"
+	resource "go.opentelemetry.io/proto/otlp/resource/v1"
+	"google.golang.org/protobuf/proto"
+)
+
+const otlpJSON = `{"resourceLogs": [{
+	"resource": {"attributes": [{"key": "service.name", "value": {"stringValue": "checkout"}}]},
+	"scopeLogs": [{
+		"scope": {"name": "my.library", "version": "1.0.0"},
+		"logRecords": [{
+			"timeUnixNano": "1544712660300000000",
+			"severityNumber": 10,
+			"severityText": "Information",
+			"traceId": "5b8efff798038103d269b633813fc60c",
+			"spanId": "eee19b7ec3c1b174",
+			"body": {"stringValue": "Example log record"},
+			"attributes": [
+				{"key": "int.attribute", "value": {"intValue": "10"}},
+				{"key": "array.attribute", "value": {"arrayValue": {"values": [{"stringValue": "many"}]}}}
+			]
+		}]
+	}]
+}]}`
+
+func TestOTLPHTTP(t *testing.T) {
+	receiver := newRowReceiver()
+	lines := receiver.lines
+	handler := handleOTLPHTTP(receiver)
+
+	request := httptest.NewRequest(http.MethodPost, "/v1/logs", strings.NewReader(otlpJSON))
+	request.Header.Set("Content-Type", "application/json")
+	response := httptest.NewRecorder()
+	handler(response, request)
+	AssertEq(t, http.StatusOK, response.Code)
+
+	row := <-lines
+	AssertEq(t, "checkout", row.Origin)
+	AssertEq(t, "2018-12-13T14:51:00.3Z", gjson.Get(row.Line, "time").String())
+	AssertEq(t, "Information", gjson.Get(row.Line, "severity").String())
+	AssertEq(t, int64(10), gjson.Get(row.Line, "severity_number").Int())
+	AssertEq(t, "Example log record", gjson.Get(row.Line, "body").String())
+	AssertEq(t, "5b8efff798038103d269b633813fc60c", gjson.Get(row.Line, "trace_id").String())
+	AssertEq(t, "eee19b7ec3c1b174", gjson.Get(row.Line, "span_id").String())
+	AssertEq(t, int64(10), gjson.Get(row.Line, `attributes.int\.attribute`).Int())
+	AssertEq(t, "many", gjson.Get(row.Line, `attributes.array\.attribute.0`).String())
+	AssertEq(t, "checkout", gjson.Get(row.Line, `resource.service\.name`).String())
+	AssertEq(t, "my.library", gjson.Get(row.Line, "scope.name").String())
+
+	export := &collogs.ExportLogsServiceRequest{ResourceLogs: []*logs.ResourceLogs{{
+		Resource: &resource.Resource{},
+		ScopeLogs: []*logs.ScopeLogs{{LogRecords: []*logs.LogRecord{{
+			SeverityNumber: logs.SeverityNumber_SEVERITY_NUMBER_WARN,
+			Body: &common.AnyValue{Value: &common.AnyValue_KvlistValue{KvlistValue: &common.KeyValueList{
+				Values: []*common.KeyValue{{
+					Key:   "msg",
+					Value: &common.AnyValue{Value: &common.AnyValue_StringValue{StringValue: "structured"}},
+				}},
+			}}},
+		}}}},
+	}}}
+	data, err := proto.Marshal(export)
+	if err != nil {
+		t.Fatal(err)
+	}
+	request = httptest.NewRequest(http.MethodPost, "/v1/logs", bytes.NewReader(data))
+	request.Header.Set("Content-Type", "application/x-protobuf")
+	response = httptest.NewRecorder()
+	handler(response, request)
+	AssertEq(t, http.StatusOK, response.Code)
+
+	row = <-lines
+	AssertEq(t, "", row.Origin)
+	AssertEq(t, "warn", gjson.Get(row.Line, "severity").String())
+	AssertEq(t, "structured", gjson.Get(row.Line, "body.msg").String())
+	AssertEq(t, false, gjson.Get(row.Line, "trace_id").Exists())
+
+	request = httptest.NewRequest(http.MethodPost, "/v1/logs", strings.NewReader("{"))
+	request.Header.Set("Content-Type", "application/json")
+	response = httptest.NewRecorder()
+	handler(response, request)
+	AssertEq(t, http.StatusBadRequest, response.Code)
+}
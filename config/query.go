@@ -0,0 +1,356 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+)
+
+// QueryError tells what is wrong with a query, and where: Pos and End are the byte offsets of the
+// offending part of the query.
+type QueryError struct {
+	Pos, End int
+	Msg      string
+}
+
+func (e *QueryError) Error() string {
+	return fmt.Sprintf("%s (at column %d)", e.Msg, e.Pos+1)
+}
+
+// ParseQuery parses a query like `level == "error" and (svc =~ "pay.*" or not msg contains
+// "timeout")` into the filters of lv, which must all pass for a row to be shown.
+//
+// A comparison is an attribute name, an operator and a value. Names that aren't a single word go
+// in backticks, and * stands for the whole row. A value is a word, like 500 or -15m, or a string in
+// double quotes, where \" and \\ are the only escapes so that regexes can be written as they are.
+// A string on its own means that the row contains it. Comparisons can be combined with and, or and
+// not, where not binds tightest and or loosest, and grouped with parentheses.
+func ParseQuery(query string, lv LogView) ([]Filter, error) {
+	tokens, err := lexQuery(query)
+	if err != nil {
+		return nil, err
+	}
+	if len(tokens) == 0 {
+		return nil, nil
+	}
+	p := &queryParser{tokens: tokens, query: query, logView: lv}
+	filter, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if t := p.peek(); t != nil {
+		return nil, t.errorf("expected and, or or the end of the query")
+	}
+	if len(filter.All) > 0 && !filter.Not {
+		return filter.All, nil
+	}
+	return []Filter{filter}, nil
+}
+
+// FormatQuery returns the query that ParseQuery would turn into filters.
+func FormatQuery(filters []Filter) string {
+	parts := make([]string, len(filters))
+	for i, filter := range filters {
+		parts[i] = formatFilter(filter, len(filters) > 1)
+	}
+	return strings.Join(parts, " and ")
+}
+
+// formatFilter formats filter, in parentheses if it is a group that would otherwise be read
+// differently, like an or group within an and group.
+func formatFilter(filter Filter, inAnd bool) string {
+	var s string
+	switch {
+	case len(filter.Any) > 0:
+		s = formatGroup(filter.Any, " or ", false, inAnd || filter.Not)
+	case len(filter.All) > 0:
+		s = formatGroup(filter.All, " and ", true, filter.Not)
+	case filter.Attr == nil && (filter.Operator == "" || filter.Operator == Contains):
+		s = quoteQueryString(filter.Term)
+	default:
+		name := "*"
+		if filter.Attr != nil {
+			name = formatQueryName(filter.Attr.Name)
+		}
+		op := filter.Operator
+		if op == "" {
+			op = Contains
+		}
+		s = name + " " + string(op) + " " + quoteQueryString(filter.Term)
+	}
+	if filter.Not {
+		return "not " + s
+	}
+	return s
+}
+
+func formatGroup(filters []Filter, separator string, inAnd, parenthesize bool) string {
+	parts := make([]string, len(filters))
+	for i, filter := range filters {
+		parts[i] = formatFilter(filter, inAnd)
+	}
+	s := strings.Join(parts, separator)
+	if parenthesize {
+		return "(" + s + ")"
+	}
+	return s
+}
+
+func formatQueryName(name string) string {
+	for _, r := range name {
+		if isQueryOperatorRune(r) || strings.ContainsRune(" \t()\"`", r) {
+			return "`" + name + "`"
+		}
+	}
+	if isQueryKeyword(name) {
+		return "`" + name + "`"
+	}
+	return name
+}
+
+// quoteQueryString quotes s, escaping only the backslashes that would otherwise be read as an
+// escape, so that regexes stay readable.
+func quoteQueryString(s string) string {
+	var b strings.Builder
+	b.WriteByte('"')
+	for i := 0; i < len(s); i++ {
+		switch {
+		case s[i] == '"':
+			b.WriteString(`\"`)
+		case s[i] == '\\' && (i+1 == len(s) || s[i+1] == '"' || s[i+1] == '\\'):
+			b.WriteString(`\\`)
+		default:
+			b.WriteByte(s[i])
+		}
+	}
+	b.WriteByte('"')
+	return b.String()
+}
+
+type queryTokenKind int
+
+const (
+	tokenWord queryTokenKind = iota
+	tokenString
+	tokenName
+	tokenOperator
+	tokenOpen
+	tokenClose
+)
+
+type queryToken struct {
+	kind     queryTokenKind
+	text     string
+	pos, end int
+}
+
+func (t *queryToken) errorf(format string, args ...any) *QueryError {
+	return &QueryError{Pos: t.pos, End: t.end, Msg: fmt.Sprintf(format, args...)}
+}
+
+// is reports whether t is the given keyword.
+func (t *queryToken) is(keyword string) bool {
+	return t != nil && t.kind == tokenWord && strings.EqualFold(t.text, keyword)
+}
+
+func isQueryOperatorRune(r rune) bool {
+	return strings.ContainsRune("=!<>~", r)
+}
+
+func isQueryKeyword(word string) bool {
+	switch strings.ToLower(word) {
+	case "and", "or", "not", "contains":
+		return true
+	}
+	return false
+}
+
+func lexQuery(query string) ([]queryToken, error) {
+	tokens := make([]queryToken, 0)
+	for i := 0; i < len(query); {
+		c := query[i]
+		start := i
+		switch {
+		case c == ' ' || c == '\t' || c == '\n':
+			i++
+		case c == '(' || c == ')':
+			kind := tokenOpen
+			if c == ')' {
+				kind = tokenClose
+			}
+			tokens = append(tokens, queryToken{kind: kind, text: string(c), pos: i, end: i + 1})
+			i++
+		case c == '"':
+			var b strings.Builder
+			for i++; i < len(query) && query[i] != '"'; i++ {
+				if query[i] == '\\' && i+1 < len(query) && (query[i+1] == '"' || query[i+1] == '\\') {
+					i++
+				}
+				b.WriteByte(query[i])
+			}
+			if i == len(query) {
+				return nil, &QueryError{Pos: start, End: i, Msg: "unterminated string"}
+			}
+			i++
+			tokens = append(tokens, queryToken{kind: tokenString, text: b.String(), pos: start, end: i})
+		case c == '`':
+			end := strings.IndexByte(query[i+1:], '`')
+			if end < 0 {
+				return nil, &QueryError{Pos: start, End: len(query), Msg: "unterminated name"}
+			}
+			i += end + 2
+			tokens = append(tokens, queryToken{kind: tokenName, text: query[start+1 : i-1], pos: start, end: i})
+		case isQueryOperatorRune(rune(c)):
+			for i < len(query) && isQueryOperatorRune(rune(query[i])) {
+				i++
+			}
+			tokens = append(tokens, queryToken{kind: tokenOperator, text: query[start:i], pos: start, end: i})
+		default:
+			for i < len(query) && !strings.ContainsRune(" \t\n()\"`", rune(query[i])) &&
+				!isQueryOperatorRune(rune(query[i])) {
+				i++
+			}
+			tokens = append(tokens, queryToken{kind: tokenWord, text: query[start:i], pos: start, end: i})
+		}
+	}
+	return tokens, nil
+}
+
+type queryParser struct {
+	tokens  []queryToken
+	next    int
+	query   string
+	logView LogView
+}
+
+func (p *queryParser) peek() *queryToken {
+	if p.next == len(p.tokens) {
+		return nil
+	}
+	return &p.tokens[p.next]
+}
+
+func (p *queryParser) take() (*queryToken, error) {
+	t := p.peek()
+	if t == nil {
+		return nil, &QueryError{Pos: len(p.query), End: len(p.query), Msg: "unexpected end of query"}
+	}
+	p.next++
+	return t, nil
+}
+
+func (p *queryParser) parseOr() (Filter, error) {
+	return p.parseList("or", p.parseAnd, func(filters []Filter) Filter { return Filter{Any: filters} })
+}
+
+func (p *queryParser) parseAnd() (Filter, error) {
+	return p.parseList("and", p.parseUnary, func(filters []Filter) Filter { return Filter{All: filters} })
+}
+
+// parseList parses operands separated by the keyword, grouping them if there is more than one.
+func (p *queryParser) parseList(
+	keyword string,
+	parseOperand func() (Filter, error),
+	group func([]Filter) Filter,
+) (Filter, error) {
+	first, err := parseOperand()
+	if err != nil {
+		return Filter{}, err
+	}
+	filters := []Filter{first}
+	for p.peek().is(keyword) {
+		p.next++
+		operand, err := parseOperand()
+		if err != nil {
+			return Filter{}, err
+		}
+		filters = append(filters, operand)
+	}
+	if len(filters) == 1 {
+		return first, nil
+	}
+	return group(filters), nil
+}
+
+func (p *queryParser) parseUnary() (Filter, error) {
+	if p.peek().is("not") {
+		p.next++
+		filter, err := p.parseUnary()
+		filter.Not = !filter.Not
+		return filter, err
+	}
+	return p.parsePrimary()
+}
+
+func (p *queryParser) parsePrimary() (Filter, error) {
+	t, err := p.take()
+	if err != nil {
+		return Filter{}, err
+	}
+	switch {
+	case t.kind == tokenOpen:
+		filter, err := p.parseOr()
+		if err != nil {
+			return Filter{}, err
+		}
+		closing, err := p.take()
+		if err != nil {
+			return Filter{}, err
+		}
+		if closing.kind != tokenClose {
+			return Filter{}, closing.errorf("expected )")
+		}
+		return filter, nil
+	case t.kind == tokenString:
+		return Filter{Term: t.text, Operator: Contains}, nil
+	case t.kind == tokenName || (t.kind == tokenWord && !isQueryKeyword(t.text)):
+		return p.parseComparison(t)
+	default:
+		return Filter{}, t.errorf("expected a comparison, a string or (")
+	}
+}
+
+// parseComparison parses the operator and value that follow the attribute name in t.
+func (p *queryParser) parseComparison(name *queryToken) (Filter, error) {
+	var attr *Attribute
+	if name.kind == tokenName || name.text != "*" {
+		var err error
+		if attr, err = p.logView.GetAttributeWithName(name.text); err != nil {
+			return Filter{}, name.errorf("no attribute named %s", name.text)
+		}
+	}
+
+	t, err := p.take()
+	if err != nil {
+		return Filter{}, err
+	}
+	var op FilterOp
+	switch {
+	case t.kind == tokenOperator:
+		if op, err = ParseFilterOp(t.text); err != nil || op == Contains || op == NotContains {
+			return Filter{}, t.errorf("unknown operator %s", t.text)
+		}
+	case t.is("contains"):
+		op = Contains
+	case t.is("not"):
+		if next := p.peek(); !next.is("contains") {
+			return Filter{}, t.errorf("expected contains after not")
+		}
+		p.next++
+		op = NotContains
+	default:
+		return Filter{}, t.errorf("expected an operator after %s", name.text)
+	}
+
+	value, err := p.take()
+	if err != nil {
+		return Filter{}, err
+	}
+	if value.kind != tokenString && value.kind != tokenWord {
+		return Filter{}, value.errorf("expected a value")
+	}
+	filter := Filter{Term: value.text, Operator: op, Attr: attr}
+	if _, err := filter.Matcher(); err != nil {
+		return Filter{}, value.errorf("%s", err)
+	}
+	return filter, nil
+}
@@ -0,0 +1,69 @@
+package config
+
+import (
+	"errors"
+	"testing"
+
+	. "github.com/torarvid/gloglog/testutil"
+)
+
+func TestParseQuery(t *testing.T) {
+	lv := LogView{Attrs: []Attribute{{Name: "level"}, {Name: "svc"}, {Name: "msg"}, {Name: "Request Time"}}}
+
+	filters, err := ParseQuery(`level == "error" and (svc =~ "pay.*" or not msg contains "timeout")`, lv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	AssertEq(t, 2, len(filters))
+	AssertEq(t, "level", filters[0].Attr.Name)
+	AssertEq(t, Equal, filters[0].Operator)
+	AssertEq(t, "error", filters[0].Term)
+	AssertEq(t, 2, len(filters[1].Any))
+	AssertEq(t, RegexEqual, filters[1].Any[0].Operator)
+	AssertEq(t, true, filters[1].Any[1].Not)
+	AssertEq(t, "timeout", filters[1].Any[1].Term)
+
+	for query, formatted := range map[string]string{
+		`level == "error" and (svc =~ "pay.*" or not msg contains "timeout")`: "",
+		`"GET" OR "POST" and level != info`:                                   `"GET" or "POST" and level != "info"`,
+		`not (level==error or level==warn)`:                                   `not (level == "error" or level == "warn")`,
+		"`Request Time` > \"-15m\" and msg not contains \"a \\\"b\\\" \\d\"":  "",
+		`* =~ "\d+ms"`:   "",
+		`((("nested")))`: `"nested"`,
+		``:               ``,
+	} {
+		filters, err := ParseQuery(query, lv)
+		if err != nil {
+			t.Fatalf("%s: %s", query, err)
+		}
+		if formatted == "" {
+			formatted = query
+		}
+		AssertEq(t, formatted, FormatQuery(filters))
+		again, err := ParseQuery(FormatQuery(filters), lv)
+		if err != nil {
+			t.Fatal(err)
+		}
+		AssertEq(t, formatted, FormatQuery(again))
+	}
+
+	for query, expected := range map[string]QueryError{
+		`level == "error`:       {Pos: 9, End: 15, Msg: "unterminated string"},
+		`nope == 1`:             {Pos: 0, End: 4, Msg: "no attribute named nope"},
+		`level = 1`:             {Pos: 6, End: 7, Msg: "unknown operator ="},
+		`level == 1 or`:         {Pos: 13, End: 13, Msg: "unexpected end of query"},
+		`(level == 1`:           {Pos: 11, End: 11, Msg: "unexpected end of query"},
+		`level == 1 level == 2`: {Pos: 11, End: 16, Msg: "expected and, or or the end of the query"},
+		`msg =~ "("`:            {Pos: 7, End: 10, Msg: "invalid regex (: error parsing regexp: missing closing ): `(`"},
+		`level`:                 {Pos: 5, End: 5, Msg: "unexpected end of query"},
+		`level == 1 and and`:    {Pos: 15, End: 18, Msg: "expected a comparison, a string or ("},
+		`level not == 1`:        {Pos: 6, End: 9, Msg: "expected contains after not"},
+	} {
+		_, err := ParseQuery(query, lv)
+		var queryErr *QueryError
+		if !errors.As(err, &queryErr) {
+			t.Fatalf("%s: expected a QueryError, got %v", query, err)
+		}
+		AssertEq(t, expected, *queryErr)
+	}
+}
@@ -0,0 +1,86 @@
+package config
+
+import (
+	"context"
+	"errors"
+	"net/http"
+)
+
+// errStopped is returned by rowReceiver.receive once the receiver has been stopped.
+var errStopped = errors.New("no longer receiving rows")
+
+// rowReceiver passes rows that servers receive, like those pushed to Ingest or to an otlpSource, on
+// to the follower that forwards them to the view.
+//
+// Its lines are never closed, as handlers that are still running may send on them. They give up
+// once the receiver is stopped instead.
+type rowReceiver struct {
+	lines   chan Row
+	stopped chan struct{}
+}
+
+func newRowReceiver() *rowReceiver {
+	return &rowReceiver{lines: make(chan Row, maxBatch), stopped: make(chan struct{})}
+}
+
+// receive sends rows on lines. It returns ctx's error if ctx is cancelled first, or errStopped if
+// the receiver is stopped.
+func (rr *rowReceiver) receive(ctx context.Context, rows []Row) error {
+	for _, row := range rows {
+		select {
+		case rr.lines <- row:
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-rr.stopped:
+			return errStopped
+		}
+	}
+	return nil
+}
+
+// stop makes handlers give up on the rows they haven't sent yet.
+func (rr *rowReceiver) stop() {
+	close(rr.stopped)
+}
+
+// httpError is an error that is reported with a status code other than 400 Bad Request.
+type httpError struct {
+	code int
+	msg  string
+}
+
+func (e httpError) Error() string {
+	return e.msg
+}
+
+// handlePost returns a handler for POST requests, whose rows are read by parse and then received.
+// Errors from parse are reported as bad requests unless they are httpErrors. Once the rows have been
+// received, reply writes the response.
+func (rr *rowReceiver) handlePost(
+	parse func(r *http.Request) ([]Row, error),
+	reply func(w http.ResponseWriter, r *http.Request),
+) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "only POST is supported", http.StatusMethodNotAllowed)
+			return
+		}
+		rows, err := parse(r)
+		if err != nil {
+			code := http.StatusBadRequest
+			var httpErr httpError
+			if errors.As(err, &httpErr) {
+				code = httpErr.code
+			}
+			http.Error(w, err.Error(), code)
+			return
+		}
+		if err := rr.receive(r.Context(), rows); err != nil {
+			if errors.Is(err, errStopped) {
+				http.Error(w, err.Error(), http.StatusServiceUnavailable)
+			}
+			return
+		}
+		reply(w, r)
+	}
+}
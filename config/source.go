@@ -0,0 +1,138 @@
+package config
+
+import (
+	"bufio"
+	"context"
+	"io"
+)
+
+// Source is where a LogView gets its rows from.
+type Source interface {
+	// Rows reads the rows that are available at the time of the call.
+	Rows() ([]Row, error)
+}
+
+// Row is one entry read from a Source.
+type Row struct {
+	// Line is the log entry as read from the source.
+	Line string
+	// Origin tells where the row was read from, for sources that read from more than one place.
+	Origin string
+	// Marker is set for rows that aren't log entries but notes from gloglog itself, such as where a
+	// followed file was rotated. The note is in Line.
+	Marker bool
+	// Context is set for rows that are shown only because they are near a row that passes the
+	// filters, and for the separators between groups of such rows.
+	Context bool
+}
+
+// MarkerRow returns a row that shows text across the table instead of a log entry.
+func MarkerRow(text string) Row {
+	return Row{Line: text, Marker: true}
+}
+
+// rowsFromLines returns a row for each line.
+func rowsFromLines(lines []string) []Row {
+	rows := make([]Row, len(lines))
+	for i, line := range lines {
+		rows[i] = Row{Line: line}
+	}
+	return rows
+}
+
+// Follower is implemented by sources that keep producing rows after the initial call to Rows.
+//
+// Follow blocks, sending new rows on updates, until ctx is cancelled or the source is exhausted.
+// Following may be stopped and started again, like when follow mode is toggled or the source is
+// restarted, and the new follower may start before the one it replaces has returned. Followers that
+// keep state between runs, like the position they have read to, or that listen on an address,
+// hold a mutex for as long as Follow runs, so that they don't overlap.
+type Follower interface {
+	Source
+	Follow(ctx context.Context, updates chan<- Update) error
+}
+
+// Update is what a Follower sends whenever it has something new for the view.
+type Update struct {
+	Rows []Row
+	// Status, if set, replaces the status the source last reported, e.g. that a command exited.
+	Status string
+}
+
+// send delivers u on updates unless ctx is cancelled first. It returns false if ctx was cancelled.
+func send(ctx context.Context, updates chan<- Update, u Update) bool {
+	select {
+	case updates <- u:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// maxBatch is the most rows forwardLines puts in a single Update.
+const maxBatch = 1000
+
+// forwardLines sends lines on updates as they arrive, batching up lines that arrive faster than the
+// view can take them. It returns when lines is closed or ctx is cancelled.
+func forwardLines(ctx context.Context, lines <-chan Row, updates chan<- Update) {
+	for {
+		var rows []Row
+		select {
+		case line, ok := <-lines:
+			if !ok {
+				return
+			}
+			rows = append(rows, line)
+		case <-ctx.Done():
+			return
+		}
+	batch:
+		for len(rows) < maxBatch {
+			select {
+			case line, ok := <-lines:
+				if !ok {
+					send(ctx, updates, Update{Rows: rows})
+					return
+				}
+				rows = append(rows, line)
+			default:
+				break batch
+			}
+		}
+		if !send(ctx, updates, Update{Rows: rows}) {
+			return
+		}
+	}
+}
+
+// scanLines reads lines from reader and sends them as rows with the given origin on lines until
+// EOF.
+func scanLines(reader io.Reader, origin string, lines chan<- Row) error {
+	buffered := bufio.NewReader(reader)
+	for {
+		line, err := buffered.ReadString('\n')
+		if line != "" {
+			lines <- Row{Line: trimEOL(line), Origin: origin}
+		}
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// Pager is implemented by sources that load rows one page at a time, where Rows returns the first
+// page. NextPage returns the page after the last one returned, or no rows once there are no more.
+type Pager interface {
+	Source
+	NextPage() ([]Row, error)
+}
+
+// AttrSuggester is implemented by sources that know what attributes suit their rows. The suggested
+// attributes are used for views that don't have any attributes yet.
+type AttrSuggester interface {
+	Source
+	SuggestedAttrs() []Attribute
+}
@@ -0,0 +1,31 @@
+package config
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	. "github.com/torarvid/gloglog/testutil"
+)
+
+func TestForwardLines(t *testing.T) {
+	lines := make(chan Row, 10)
+	go func() {
+		defer close(lines)
+		scanLines(strings.NewReader("one\ntwo\r\nthree"), "", lines)
+	}()
+
+	updates := make(chan Update)
+	go func() {
+		defer close(updates)
+		forwardLines(context.Background(), lines, updates)
+	}()
+
+	rows := make([]Row, 0)
+	for update := range updates {
+		rows = append(rows, update.Rows...)
+	}
+	AssertEq(t, 3, len(rows))
+	AssertEq(t, "two", rows[1].Line)
+	AssertEq(t, "three", rows[2].Line)
+}
@@ -0,0 +1,139 @@
+package config
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	_ "modernc.org/sqlite"
+)
+
+// sqlSource reads rows from a SQL query against a SQLite database.
+//
+// Options:
+//   - database: the database file
+//   - query: the query to run
+//   - start, end: bound to the :start and :end parameters of the query, if it uses them; RFC3339
+//     times or durations relative to now
+//   - time_format: how :start and :end are passed to the query; rfc3339 (the default), unix or
+//     unixms
+//
+// Each result row becomes a JSON object keyed by column name, in column order, so that json(...)
+// selectors work on it like on any JSON log line.
+type sqlSource struct {
+	database   string
+	query      string
+	params     []any
+	timeFormat string
+}
+
+func fromSQL(logView LogView) *sqlSource {
+	database, exists := logView.Options["database"]
+	if !exists {
+		panic("database not found")
+	}
+	query, exists := logView.Options["query"]
+	if !exists {
+		panic("query not found")
+	}
+	timeFormat := logView.Options["time_format"]
+	if timeFormat == "" {
+		timeFormat = "rfc3339"
+	}
+	ss := &sqlSource{database: database, query: query, timeFormat: timeFormat}
+
+	now := time.Now()
+	for _, name := range []string{"start", "end"} {
+		if _, exists := logView.Options[name]; !exists || !strings.Contains(query, ":"+name) {
+			continue
+		}
+		bound := logView.timeOption(name, "", now)
+		ss.params = append(ss.params, sql.Named(name, ss.formatTime(bound)))
+	}
+	return ss
+}
+
+func (ss *sqlSource) formatTime(t time.Time) any {
+	switch ss.timeFormat {
+	case "unix":
+		return t.Unix()
+	case "unixms":
+		return t.UnixMilli()
+	case "rfc3339":
+		return t.UTC().Format(time.RFC3339Nano)
+	default:
+		panic("Unknown time_format: " + ss.timeFormat)
+	}
+}
+
+func (ss *sqlSource) Rows() ([]Row, error) {
+	fmt.Fprintf(os.Stderr, "Querying database '%s'", ss.database)
+	db, err := sql.Open("sqlite", ss.database)
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	result, err := db.Query(ss.query, ss.params...)
+	if err != nil {
+		return nil, err
+	}
+	defer result.Close()
+	columns, err := result.Columns()
+	if err != nil {
+		return nil, err
+	}
+
+	rows := make([]Row, 0)
+	values := make([]any, len(columns))
+	pointers := make([]any, len(columns))
+	for i := range values {
+		pointers[i] = &values[i]
+	}
+	for result.Next() {
+		if err := result.Scan(pointers...); err != nil {
+			return nil, err
+		}
+		line, err := jsonObject(columns, values)
+		if err != nil {
+			return nil, err
+		}
+		rows = append(rows, Row{Line: line})
+	}
+	return rows, result.Err()
+}
+
+// jsonObject returns a JSON object with the given keys and values, keeping the order of the keys.
+func jsonObject(keys []string, values []any) (string, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, key := range keys {
+		value := values[i]
+		switch v := value.(type) {
+		case []byte:
+			value = string(v)
+		case time.Time:
+			value = v.Format(time.RFC3339Nano)
+		}
+		keyData, err := json.Marshal(key)
+		if err != nil {
+			return "", err
+		}
+		valueData, err := json.Marshal(value)
+		if err != nil {
+			return "", err
+		}
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		buf.Write(keyData)
+		buf.WriteByte(':')
+		buf.Write(valueData)
+	}
+	buf.WriteByte('}')
+	return buf.String(), nil
+}
@@ -0,0 +1,52 @@
+package config
+
+import (
+	"database/sql"
+	"path/filepath"
+	"testing"
+
+	. "github.com/torarvid/gloglog/testutil"
+)
+
+func TestSQLSource(t *testing.T) {
+	database := filepath.Join(t.TempDir(), "audit.db")
+	db, err := sql.Open("sqlite", database)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = db.Exec(`
+		CREATE TABLE audit (ts TEXT, level TEXT, msg TEXT, n INTEGER);
+		INSERT INTO audit VALUES
+			('2020-01-01T00:00:00Z', 'info', 'too early', 1),
+			('2020-01-01T01:00:00Z', 'error', 'in range', 2),
+			('2020-01-01T03:00:00Z', 'info', 'too late', NULL);
+	`)
+	db.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	source := fromSQL(LogView{Options: map[string]string{
+		"database": database,
+		"query":    "SELECT ts, level, msg, n FROM audit WHERE ts >= :start AND ts <= :end",
+		"start":    "2020-01-01T00:30:00Z",
+		"end":      "2020-01-01T02:00:00Z",
+	}})
+	rows, err := source.Rows()
+	if err != nil {
+		t.Fatal(err)
+	}
+	AssertEq(t, 1, len(rows))
+	AssertEq(t, `{"ts":"2020-01-01T01:00:00Z","level":"error","msg":"in range","n":2}`, rows[0].Line)
+
+	source = fromSQL(LogView{Options: map[string]string{
+		"database": database,
+		"query":    "SELECT msg, n FROM audit ORDER BY ts DESC",
+	}})
+	rows, err = source.Rows()
+	if err != nil {
+		t.Fatal(err)
+	}
+	AssertEq(t, 3, len(rows))
+	AssertEq(t, `{"msg":"too late","n":null}`, rows[0].Line)
+}
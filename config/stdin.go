@@ -0,0 +1,78 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+)
+
+// stdinSource reads rows from standard input, typically a pipe like `kubectl logs -f pod | gloglog`.
+//
+// Nothing is available up front; all rows arrive through Follow. Standard input can only be read
+// once, so a single goroutine reads it for the lifetime of the program. While the view isn't
+// following, lines queue up in the pipe.
+//
+// read turns what is read into rows, which by default are the lines of input.
+type stdinSource struct {
+	read  func(reader io.Reader, lines chan<- Row) error
+	once  sync.Once
+	lines chan Row
+	err   error
+}
+
+func fromStdin(logView LogView) *stdinSource {
+	return newStdinSource(func(reader io.Reader, lines chan<- Row) error {
+		return scanLines(reader, "", lines)
+	})
+}
+
+func newStdinSource(read func(reader io.Reader, lines chan<- Row) error) *stdinSource {
+	return &stdinSource{read: read, lines: make(chan Row, maxBatch)}
+}
+
+func (ss *stdinSource) Rows() ([]Row, error) {
+	fmt.Fprint(os.Stderr, "Reading from stdin")
+	return []Row{}, nil
+}
+
+func (ss *stdinSource) Follow(ctx context.Context, updates chan<- Update) error {
+	ss.once.Do(func() {
+		go func() {
+			defer close(ss.lines)
+			ss.err = ss.read(os.Stdin, ss.lines)
+		}()
+	})
+	forwardLines(ctx, ss.lines, updates)
+	if ctx.Err() != nil {
+		return nil
+	}
+	return ss.err
+}
+
+// StdinIsPiped reports whether standard input is a pipe or a file rather than a terminal.
+func StdinIsPiped() bool {
+	info, err := os.Stdin.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice == 0
+}
+
+// ReadingPipedInput returns lv changed to read from standard input, for when it is a pipe. A
+// journald view reads the journal from the pipe, and any other view becomes a stdin view. Options
+// are copied, so that changes to them aren't saved to the view that was loaded.
+func (lv LogView) ReadingPipedInput() LogView {
+	options := make(map[string]string, len(lv.Options)+1)
+	for key, value := range lv.Options {
+		options[key] = value
+	}
+	lv.Options = options
+	if lv.SourceId == "journald" {
+		lv.Options["filename"] = "-"
+	} else {
+		lv.SourceId = "stdin"
+	}
+	return lv
+}
@@ -0,0 +1,359 @@
+package config
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// syslogSource receives syslog messages over the network, e.g. from network devices or daemons
+// pointed at gloglog while debugging.
+//
+// Options:
+//   - address: where to listen (default localhost:5514)
+//   - protocol: udp (the default), tcp or both
+//
+// Both RFC 5424 and RFC 3164 (BSD) messages are understood. Over TCP, messages are either framed by
+// newlines or octet-counted. Each message becomes a JSON row with the fields facility, severity,
+// priority, version, timestamp, hostname, app_name, procid, msgid, structured_data and message, and
+// its Origin is the address of the sender.
+type syslogSource struct {
+	address  string
+	protocol string
+
+	mu sync.Mutex
+}
+
+var syslogFacilities = []string{
+	"kern", "user", "mail", "daemon", "auth", "syslog", "lpr", "news", "uucp", "cron", "authpriv",
+	"ftp", "ntp", "security", "console", "solaris-cron",
+	"local0", "local1", "local2", "local3", "local4", "local5", "local6", "local7",
+}
+
+var syslogSeverities = []string{
+	"emerg", "alert", "crit", "err", "warning", "notice", "info", "debug",
+}
+
+type syslogMessage struct {
+	Facility       string                       `json:"facility,omitempty"`
+	Severity       string                       `json:"severity,omitempty"`
+	Priority       *int                         `json:"priority,omitempty"`
+	Version        int                          `json:"version,omitempty"`
+	Timestamp      string                       `json:"timestamp,omitempty"`
+	Hostname       string                       `json:"hostname,omitempty"`
+	AppName        string                       `json:"app_name,omitempty"`
+	ProcID         string                       `json:"procid,omitempty"`
+	MsgID          string                       `json:"msgid,omitempty"`
+	StructuredData map[string]map[string]string `json:"structured_data,omitempty"`
+	Message        string                       `json:"message"`
+}
+
+func fromSyslog(logView LogView) *syslogSource {
+	address := logView.Options["address"]
+	if address == "" {
+		address = "localhost:5514"
+	}
+	protocol := logView.Options["protocol"]
+	if protocol == "" {
+		protocol = "udp"
+	}
+	if protocol != "udp" && protocol != "tcp" && protocol != "both" {
+		panic("Unknown protocol: " + protocol)
+	}
+	return &syslogSource{address: address, protocol: protocol}
+}
+
+func (ss *syslogSource) Rows() ([]Row, error) {
+	fmt.Fprintf(os.Stderr, "Listening for syslog on %s (%s)", ss.address, ss.protocol)
+	return []Row{}, nil
+}
+
+func (ss *syslogSource) Follow(ctx context.Context, updates chan<- Update) error {
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+
+	lines := make(chan Row, maxBatch)
+	closers := make([]io.Closer, 0, 2)
+	var wg sync.WaitGroup
+	if ss.protocol == "udp" || ss.protocol == "both" {
+		conn, err := net.ListenPacket("udp", ss.address)
+		if err != nil {
+			return err
+		}
+		closers = append(closers, conn)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			receiveSyslogPackets(conn, lines)
+		}()
+	}
+	if ss.protocol == "tcp" || ss.protocol == "both" {
+		listener, err := net.Listen("tcp", ss.address)
+		if err != nil {
+			for _, closer := range closers {
+				closer.Close()
+			}
+			wg.Wait()
+			return err
+		}
+		closers = append(closers, listener)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			acceptSyslogConns(ctx, listener, lines)
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(lines)
+	}()
+
+	status := fmt.Sprintf("Listening for syslog on %s (%s)", ss.address, ss.protocol)
+	if send(ctx, updates, Update{Status: status}) {
+		forwardLines(ctx, lines, updates)
+	}
+	for _, closer := range closers {
+		closer.Close()
+	}
+	// Let the receivers finish, so that the address is free once Follow returns
+	for range lines {
+	}
+	return nil
+}
+
+// maxSyslogMessage is the size of the largest message that is received, which is as much as a UDP
+// packet can hold.
+const maxSyslogMessage = 64 * 1024
+
+func receiveSyslogPackets(conn net.PacketConn, lines chan<- Row) {
+	buf := make([]byte, maxSyslogMessage)
+	for {
+		n, addr, err := conn.ReadFrom(buf)
+		if err != nil {
+			return
+		}
+		message := strings.TrimRight(string(buf[:n]), "\r\n\x00")
+		lines <- Row{Line: parseSyslog(message, time.Now()), Origin: addr.String()}
+	}
+}
+
+func acceptSyslogConns(ctx context.Context, listener net.Listener, lines chan<- Row) {
+	var wg sync.WaitGroup
+	defer wg.Wait()
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer conn.Close()
+			go func() {
+				<-ctx.Done()
+				conn.Close()
+			}()
+			readSyslogStream(conn, conn.RemoteAddr().String(), lines)
+		}()
+	}
+}
+
+// readSyslogStream reads messages from a TCP stream, where each message is either octet-counted
+// ("<length> <message>") or terminated by a newline. Octet-counted messages may be no longer than
+// maxSyslogMessage.
+func readSyslogStream(reader io.Reader, origin string, lines chan<- Row) error {
+	buffered := bufio.NewReader(reader)
+	for {
+		first, err := buffered.Peek(1)
+		if err != nil {
+			return err
+		}
+		var message string
+		if first[0] >= '1' && first[0] <= '9' {
+			// ReadSlice stops at the end of the buffer, so endless digits don't use up memory either
+			slice, err := buffered.ReadSlice(' ')
+			if err != nil {
+				return err
+			}
+			length := string(slice)
+			n, err := strconv.Atoi(strings.TrimSuffix(length, " "))
+			if err != nil || n > maxSyslogMessage {
+				return fmt.Errorf("invalid syslog frame length: %s", length)
+			}
+			data := make([]byte, n)
+			if _, err := io.ReadFull(buffered, data); err != nil {
+				return err
+			}
+			message = string(data)
+		} else {
+			message, err = buffered.ReadString('\n')
+			if err != nil && !(errors.Is(err, io.EOF) && message != "") {
+				return err
+			}
+		}
+		message = strings.TrimRight(message, "\r\n\x00")
+		if message != "" {
+			lines <- Row{Line: parseSyslog(message, time.Now()), Origin: origin}
+		}
+	}
+}
+
+// parseSyslog parses an RFC 5424 or RFC 3164 message into a JSON object. A message that can't be
+// parsed is kept whole in the message field.
+func parseSyslog(raw string, now time.Time) string {
+	msg, err := parseSyslogMessage(raw, now)
+	if err != nil {
+		msg = syslogMessage{Message: raw}
+	}
+	data, _ := json.Marshal(msg)
+	return string(data)
+}
+
+func parseSyslogMessage(raw string, now time.Time) (syslogMessage, error) {
+	var msg syslogMessage
+	if !strings.HasPrefix(raw, "<") {
+		return msg, errors.New("missing priority")
+	}
+	end := strings.IndexByte(raw, '>')
+	if end < 2 || end > 4 {
+		return msg, errors.New("invalid priority")
+	}
+	priority, err := strconv.Atoi(raw[1:end])
+	if err != nil || priority > 191 {
+		return msg, errors.New("invalid priority")
+	}
+	msg.Priority = &priority
+	msg.Facility = syslogFacilities[priority/8]
+	msg.Severity = syslogSeverities[priority%8]
+	rest := raw[end+1:]
+
+	if strings.HasPrefix(rest, "1 ") {
+		return msg, parseRFC5424(&msg, rest[2:])
+	}
+	parseRFC3164(&msg, rest, now)
+	return msg, nil
+}
+
+// parseRFC5424 parses what follows "<PRI>1 ":
+// TIMESTAMP HOSTNAME APP-NAME PROCID MSGID STRUCTURED-DATA [MSG]
+func parseRFC5424(msg *syslogMessage, rest string) error {
+	msg.Version = 1
+	fields := make([]string, 5)
+	for i := range fields {
+		field, remaining, found := strings.Cut(rest, " ")
+		if !found && i < len(fields)-1 {
+			return errors.New("truncated header")
+		}
+		if field != "-" {
+			fields[i] = field
+		}
+		rest = remaining
+	}
+	if fields[0] != "" {
+		ts, err := time.Parse(time.RFC3339Nano, fields[0])
+		if err != nil {
+			return err
+		}
+		msg.Timestamp = ts.Format(time.RFC3339Nano)
+	}
+	msg.Hostname, msg.AppName, msg.ProcID, msg.MsgID = fields[1], fields[2], fields[3], fields[4]
+
+	if strings.HasPrefix(rest, "-") {
+		rest = rest[1:]
+	} else {
+		sd, remaining, err := parseStructuredData(rest)
+		if err != nil {
+			return err
+		}
+		msg.StructuredData, rest = sd, remaining
+	}
+	msg.Message = strings.TrimPrefix(strings.TrimPrefix(rest, " "), "\ufeff")
+	return nil
+}
+
+// parseStructuredData parses one or more SD-ELEMENTs like [id param="value" ...], returning them
+// along with whatever follows them.
+func parseStructuredData(s string) (map[string]map[string]string, string, error) {
+	sd := make(map[string]map[string]string)
+	for strings.HasPrefix(s, "[") {
+		s = s[1:]
+		idEnd := strings.IndexAny(s, " ]")
+		if idEnd < 1 {
+			return nil, "", errors.New("invalid structured data")
+		}
+		params := make(map[string]string)
+		sd[s[:idEnd]] = params
+		s = s[idEnd:]
+		for strings.HasPrefix(s, " ") {
+			s = s[1:]
+			name, value, found := strings.Cut(s, `="`)
+			if !found {
+				return nil, "", errors.New("invalid structured data parameter")
+			}
+			s = value
+			var b strings.Builder
+			i := 0
+			for ; i < len(s) && s[i] != '"'; i++ {
+				if s[i] == '\\' && i+1 < len(s) && strings.IndexByte(`"\]`, s[i+1]) >= 0 {
+					i++
+				}
+				b.WriteByte(s[i])
+			}
+			if i == len(s) {
+				return nil, "", errors.New("unterminated structured data parameter")
+			}
+			params[name] = b.String()
+			s = s[i+1:]
+		}
+		if !strings.HasPrefix(s, "]") {
+			return nil, "", errors.New("unterminated structured data element")
+		}
+		s = s[1:]
+	}
+	return sd, s, nil
+}
+
+// parseRFC3164 parses what follows "<PRI>" in a BSD syslog message: TIMESTAMP HOSTNAME TAG: MSG.
+// Senders are lax about this format, so whatever can't be recognized ends up in the message.
+func parseRFC3164(msg *syslogMessage, rest string, now time.Time) {
+	if len(rest) >= len(time.Stamp) {
+		if ts, err := time.ParseInLocation(time.Stamp, rest[:len(time.Stamp)], now.Location()); err == nil {
+			// The timestamp has no year; pick the one that puts it closest to now
+			ts = ts.AddDate(now.Year(), 0, 0)
+			if ts.After(now.AddDate(0, 1, 0)) {
+				ts = ts.AddDate(-1, 0, 0)
+			}
+			msg.Timestamp = ts.Format(time.RFC3339Nano)
+			rest = strings.TrimPrefix(rest[len(time.Stamp):], " ")
+			if hostname, remaining, found := strings.Cut(rest, " "); found && !isSyslogTag(hostname) {
+				msg.Hostname, rest = hostname, remaining
+			}
+		}
+	}
+
+	tag, remaining, found := strings.Cut(rest, " ")
+	if found && isSyslogTag(tag) {
+		tag = strings.TrimSuffix(tag, ":")
+		if name, pid, hasPid := strings.Cut(tag, "["); hasPid {
+			msg.AppName, msg.ProcID = name, strings.TrimSuffix(pid, "]")
+		} else {
+			msg.AppName = tag
+		}
+		rest = remaining
+	}
+	msg.Message = rest
+}
+
+// isSyslogTag reports whether s looks like the TAG of a BSD syslog message, e.g. "sshd[123]:".
+func isSyslogTag(s string) bool {
+	return strings.HasSuffix(s, ":") && len(s) > 1 && len(s) <= 48+1
+}
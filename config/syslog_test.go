@@ -0,0 +1,65 @@
+package config
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/tidwall/gjson"
+	. "github.com/torarvid/gloglog/testutil"
+)
+
+func TestParseSyslog(t *testing.T) {
+	now := time.Date(2022, 1, 10, 12, 0, 0, 0, time.UTC)
+
+	rfc5424 := parseSyslog(`<165>1 2003-10-11T22:14:15.003Z mymachine.example.com evntslog - ID47 `+
+		`[exampleSDID@32473 iut="3" eventSource="App\"lication" eventID="1011"][examplePriority@32473 class="high"] `+
+		"\ufeffAn application event log entry...", now)
+	AssertEq(t, "local4", gjson.Get(rfc5424, "facility").String())
+	AssertEq(t, "notice", gjson.Get(rfc5424, "severity").String())
+	AssertEq(t, "2003-10-11T22:14:15.003Z", gjson.Get(rfc5424, "timestamp").String())
+	AssertEq(t, "mymachine.example.com", gjson.Get(rfc5424, "hostname").String())
+	AssertEq(t, "evntslog", gjson.Get(rfc5424, "app_name").String())
+	AssertEq(t, false, gjson.Get(rfc5424, "procid").Exists())
+	AssertEq(t, "ID47", gjson.Get(rfc5424, "msgid").String())
+	AssertEq(t, `App"lication`, gjson.Get(rfc5424, "structured_data.exampleSDID@32473.eventSource").String())
+	AssertEq(t, "high", gjson.Get(rfc5424, "structured_data.examplePriority@32473.class").String())
+	AssertEq(t, "An application event log entry...", gjson.Get(rfc5424, "message").String())
+
+	rfc3164 := parseSyslog("<34>Oct 11 22:14:15 mymachine su[42]: 'su root' failed on /dev/pts/8", now)
+	AssertEq(t, "auth", gjson.Get(rfc3164, "facility").String())
+	AssertEq(t, "crit", gjson.Get(rfc3164, "severity").String())
+	AssertEq(t, "2021-10-11T22:14:15Z", gjson.Get(rfc3164, "timestamp").String())
+	AssertEq(t, "mymachine", gjson.Get(rfc3164, "hostname").String())
+	AssertEq(t, "su", gjson.Get(rfc3164, "app_name").String())
+	AssertEq(t, "42", gjson.Get(rfc3164, "procid").String())
+	AssertEq(t, "'su root' failed on /dev/pts/8", gjson.Get(rfc3164, "message").String())
+
+	garbage := parseSyslog("not syslog at all", now)
+	AssertEq(t, "not syslog at all", gjson.Get(garbage, "message").String())
+}
+
+func TestReadSyslogStream(t *testing.T) {
+	stream := "20 <13>1 - - - - - - hi\n" + "<13>Jan  1 00:00:00 host app: there\n"
+	lines := make(chan Row, 10)
+	readSyslogStream(strings.NewReader(stream), "remote", lines)
+	close(lines)
+
+	messages := make([]string, 0)
+	for row := range lines {
+		messages = append(messages, gjson.Get(row.Line, "message").String())
+		AssertEq(t, "remote", row.Origin)
+	}
+	AssertEq(t, 2, len(messages))
+	AssertEq(t, "hi", messages[0])
+	AssertEq(t, "there", messages[1])
+}
+
+func TestReadSyslogStreamTooLong(t *testing.T) {
+	lines := make(chan Row, 10)
+	err := readSyslogStream(strings.NewReader("99999999999 <13>1 - - - - - - hi"), "remote", lines)
+	if err == nil || !strings.Contains(err.Error(), "invalid syslog frame length") {
+		t.Errorf("Expected a too long frame to be rejected, got %v", err)
+	}
+	AssertEq(t, 0, len(lines))
+}
@@ -0,0 +1,127 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// TimeRange limits a view to the rows whose time, as extracted by the attribute named Attr, is
+// between From and To, inclusive. Without Attr, the view's first attribute of type time is used.
+// Either end can be left empty for no limit. See ParseTimeBound for what the ends can look like;
+// relative ones are resolved when the range is applied.
+type TimeRange struct {
+	Attr string `toml:",omitempty"`
+	From string `toml:",omitempty"`
+	To   string `toml:",omitempty"`
+}
+
+// Attribute returns the attribute of lv that the range applies to, which must be of type time.
+func (tr TimeRange) Attribute(lv LogView) (*Attribute, error) {
+	if tr.Attr == "" {
+		for _, attr := range lv.Attrs {
+			if attr.Type == "time" {
+				return &attr, nil
+			}
+		}
+		return nil, fmt.Errorf("no attribute of type time")
+	}
+	attr, err := lv.GetAttributeWithName(tr.Attr)
+	if err != nil {
+		return nil, err
+	}
+	if attr.Type != "time" {
+		return nil, fmt.Errorf("attribute %s is not of type time", attr.Name)
+	}
+	return attr, nil
+}
+
+// Matcher returns a function that tells whether a value, i.e. what the range's attribute extracts
+// from a row, is an RFC3339 time within the range. Values that aren't times are outside of it.
+func (tr TimeRange) Matcher(now time.Time) (func(value string) bool, error) {
+	var from, to time.Time
+	var err error
+	if tr.From != "" {
+		if from, err = ParseTimeBound(tr.From, now); err != nil {
+			return nil, err
+		}
+	}
+	if tr.To != "" {
+		if to, err = ParseTimeBound(tr.To, now); err != nil {
+			return nil, err
+		}
+	}
+	return func(value string) bool {
+		t, err := time.Parse(time.RFC3339Nano, value)
+		if err != nil {
+			return false
+		}
+		return (from.IsZero() || !t.Before(from)) && (to.IsZero() || !t.After(to))
+	}, nil
+}
+
+// ParseTimeBound parses one end of a time range. It can be an absolute RFC3339 time, "now", a
+// duration relative to now such as "-15m", or a time of day such as "today 09:00", "yesterday
+// 14:02:30" or just "14:02" (which means today). "today" and "yesterday" on their own mean
+// midnight. Times of day are in the same location as now.
+func ParseTimeBound(value string, now time.Time) (time.Time, error) {
+	if value == "now" {
+		return now, nil
+	}
+	if t, err := time.Parse(time.RFC3339Nano, value); err == nil {
+		return t, nil
+	}
+	if d, err := time.ParseDuration(value); err == nil {
+		return now.Add(d), nil
+	}
+	if t, ok := parseTimeOfDay(value, now); ok {
+		return t, nil
+	}
+	return time.Time{}, fmt.Errorf("invalid time: %s", value)
+}
+
+// parseTimeOfDay parses values like "today 09:00", "yesterday" or "14:02:30".
+func parseTimeOfDay(value string, now time.Time) (time.Time, bool) {
+	fields := strings.Fields(strings.ToLower(value))
+	if len(fields) == 0 || len(fields) > 2 {
+		return time.Time{}, false
+	}
+	day := now
+	switch fields[0] {
+	case "today":
+		fields = fields[1:]
+	case "yesterday":
+		day = now.AddDate(0, 0, -1)
+		fields = fields[1:]
+	default:
+		if len(fields) > 1 {
+			return time.Time{}, false
+		}
+	}
+	midnight := time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, now.Location())
+	if len(fields) == 0 {
+		return midnight, true
+	}
+	for _, layout := range []string{"15:04", "15:04:05"} {
+		if clock, err := time.Parse(layout, fields[0]); err == nil {
+			sinceMidnight := time.Duration(clock.Hour())*time.Hour +
+				time.Duration(clock.Minute())*time.Minute +
+				time.Duration(clock.Second())*time.Second
+			return midnight.Add(sinceMidnight), true
+		}
+	}
+	return time.Time{}, false
+}
+
+// timeOption parses the time bound in Options[key], or def if the option isn't set.
+func (lv LogView) timeOption(key string, def string, now time.Time) time.Time {
+	value, exists := lv.Options[key]
+	if !exists {
+		value = def
+	}
+	t, err := ParseTimeBound(value, now)
+	if err != nil {
+		panic(fmt.Sprintf("%s: %s", key, err))
+	}
+	return t
+}
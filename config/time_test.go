@@ -0,0 +1,63 @@
+package config
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/torarvid/gloglog/testutil"
+)
+
+func TestParseTimeBound(t *testing.T) {
+	now := time.Date(2024, 3, 1, 14, 30, 0, 0, time.UTC)
+	for value, expected := range map[string]string{
+		"now":                  "2024-03-01T14:30:00Z",
+		"-15m":                 "2024-03-01T14:15:00Z",
+		"2024-01-02T03:04:05Z": "2024-01-02T03:04:05Z",
+		"today":                "2024-03-01T00:00:00Z",
+		"today 09:00":          "2024-03-01T09:00:00Z",
+		"Yesterday 23:59:30":   "2024-02-29T23:59:30Z",
+		"14:02":                "2024-03-01T14:02:00Z",
+	} {
+		bound, err := ParseTimeBound(value, now)
+		if err != nil {
+			t.Fatal(err)
+		}
+		AssertEq(t, expected, bound.Format(time.RFC3339))
+	}
+	for _, value := range []string{"tomorrow", "today 25:00", "09:00 today", "14"} {
+		if _, err := ParseTimeBound(value, now); err == nil {
+			t.Errorf("%s: expected an error", value)
+		}
+	}
+}
+
+func TestTimeRangeMatcher(t *testing.T) {
+	now := time.Date(2024, 3, 1, 14, 30, 0, 0, time.UTC)
+	matches, err := TimeRange{Attr: "Time", From: "14:02", To: "14:10"}.Matcher(now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	AssertEq(t, true, matches("2024-03-01T14:02:00Z"))
+	AssertEq(t, true, matches("2024-03-01T15:05:00+01:00"))
+	AssertEq(t, false, matches("2024-03-01T14:10:00.001Z"))
+	AssertEq(t, false, matches("not a time"))
+
+	matches, err = TimeRange{Attr: "Time", From: "-15m"}.Matcher(now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	AssertEq(t, true, matches("2099-01-01T00:00:00Z"))
+	AssertEq(t, false, matches("2024-03-01T14:14:59Z"))
+
+	_, err = TimeRange{Attr: "Time", To: "soon"}.Matcher(now)
+	AssertEq(t, "invalid time: soon", err.Error())
+
+	lv := LogView{Attrs: []Attribute{{Name: "Level"}, {Name: "Time", Type: "time"}}}
+	attr, err := TimeRange{}.Attribute(lv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	AssertEq(t, "Time", attr.Name)
+	_, err = TimeRange{Attr: "Level"}.Attribute(lv)
+	AssertEq(t, "attribute Level is not of type time", err.Error())
+}
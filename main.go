@@ -1,6 +1,7 @@
 package main
 
 import (
+	"flag"
 	"log/slog"
 	"os"
 	"time"
@@ -15,16 +16,28 @@ var (
 )
 
 func main() {
+	ingestAddress := flag.String("ingest", "",
+		"accept rows POSTed as newline-delimited JSON or JSON arrays on this address, e.g. localhost:8080")
+	flag.Parse()
 	initLogger()
+	stdinIsPiped := config.StdinIsPiped()
 	config := config.Load()
 	cfgLoadTime := time.Since(appStartTime)
 	slog.Info("Config loaded in", "time", cfgLoadTime)
-	view := config.GetActiveView()
+	view := *config.GetActiveView()
+	options := []tea.ProgramOption{tea.WithAltScreen()}
+	if stdinIsPiped {
+		// Rows come from the pipe, so the keyboard has to be read from the terminal directly
+		view = view.ReadingPipedInput()
+		options = append(options, tea.WithInputTTY())
+	}
 
-	m := newModel(*view)
+	m := newModel(view)
+	m.ingestAddress = *ingestAddress
+	m.sourceOverridden = stdinIsPiped
 	modelInitTime := time.Since(appStartTime) - cfgLoadTime
 	slog.Info("Model initialized in", "time", modelInitTime)
-	if err := tea.NewProgram(m, tea.WithAltScreen()).Start(); err != nil {
+	if err := tea.NewProgram(m, options...).Start(); err != nil {
 		slog.Info("Error running program:", "error", err)
 		os.Exit(1)
 	}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"container/heap"
+	"time"
+
+	"github.com/torarvid/gloglog/config"
+)
+
+// mergeByTime interleaves rows read from several origins into one stream ordered by the time in
+// attr. Rows are expected to come grouped by origin, with each group already in order.
+//
+// Rows where attr doesn't yield a time stay right after the row before them in their group, so that
+// e.g. stack traces aren't torn apart. If attr is nil, rows are returned as is.
+func mergeByTime(rows []config.Row, attr *config.Attribute) []config.Row {
+	if attr == nil || len(rows) == 0 {
+		return rows
+	}
+	getter := valueGetterFromSelectors(attr.Selectors, "", nil)
+
+	groups := make(rowGroups, 0)
+	start := 0
+	for i := 1; i <= len(rows); i++ {
+		if i == len(rows) || rows[i].Origin != rows[start].Origin {
+			groups = append(groups, newRowGroup(rows[start:i], len(groups), getter))
+			start = i
+		}
+	}
+	heap.Init(&groups)
+
+	merged := make([]config.Row, 0, len(rows))
+	for len(groups) > 0 {
+		group := groups[0]
+		merged = append(merged, group.rows[0])
+		group.rows, group.times = group.rows[1:], group.times[1:]
+		if len(group.rows) == 0 {
+			heap.Pop(&groups)
+		} else {
+			heap.Fix(&groups, 0)
+		}
+	}
+	return merged
+}
+
+// rowGroup is the not yet merged rows from one origin, along with the time of each row. index is
+// where the group was among the rows, which decides between groups whose rows have the same time.
+type rowGroup struct {
+	rows  []config.Row
+	times []time.Time
+	index int
+}
+
+func newRowGroup(rows []config.Row, index int, getter func(string) string) *rowGroup {
+	times := make([]time.Time, len(rows))
+	var last time.Time
+	for i, row := range rows {
+		if t, err := time.Parse(time.RFC3339Nano, getter(row.Line)); err == nil {
+			last = t
+		}
+		times[i] = last
+	}
+	return &rowGroup{rows: rows, times: times, index: index}
+}
+
+// rowGroups is a heap of row groups, ordered by the time of their first row, and then by where the
+// groups were, so that rows without times keep their order.
+type rowGroups []*rowGroup
+
+func (g rowGroups) Len() int { return len(g) }
+func (g rowGroups) Less(i, j int) bool {
+	if c := g[i].times[0].Compare(g[j].times[0]); c != 0 {
+		return c < 0
+	}
+	return g[i].index < g[j].index
+}
+func (g rowGroups) Swap(i, j int) { g[i], g[j] = g[j], g[i] }
+func (g *rowGroups) Push(x any)   { *g = append(*g, x.(*rowGroup)) }
+func (g *rowGroups) Pop() any {
+	old := *g
+	last := old[len(old)-1]
+	*g = old[:len(old)-1]
+	return last
+}
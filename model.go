@@ -1,55 +1,115 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log/slog"
 	"os"
+	"regexp"
+	"sort"
+	"strconv"
 	"strings"
 	"time"
 
+	"github.com/charmbracelet/bubbles/textinput"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
-	"github.com/tidwall/gjson"
 	"github.com/torarvid/gloglog/config"
 	"github.com/torarvid/gloglog/schema"
 	"github.com/torarvid/gloglog/search"
 	"github.com/torarvid/gloglog/table"
+	"github.com/torarvid/gloglog/timerange"
 )
 
 var baseStyle = lipgloss.NewStyle().
 	BorderStyle(lipgloss.RoundedBorder()).
 	BorderForeground(lipgloss.Color("240"))
 
+var queryErrorStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("9"))
+
+var statusStyle = lipgloss.NewStyle().
+	Foreground(lipgloss.Color("240")).
+	PaddingLeft(1).
+	MaxHeight(1)
+
 const (
 	stateTable = iota
 	stateZoomRow
 	stateSchema
 	stateSearch
+	stateQuery
+	stateTimeRange
+	stateFind
 )
 
 type model struct {
-	table        table.Model[string]
-	schema       schema.Model
-	rows         []string
-	filteredRows []string
-	view         config.LogView
-	filters      []RowFilter
-	search       search.Model
-	state        int
-	termWidth    int
-	termHeight   int
+	table         table.Model[config.Row]
+	schema        schema.Model
+	source        config.Source
+	stopFollowing context.CancelFunc
+	// sourceOverridden is set when the view reads from another source than the saved view does,
+	// like piped input, so that options that belong to the saved view's source aren't saved.
+	sourceOverridden bool
+	updates          <-chan config.Update
+	// ingestAddress is where rows can be pushed into the session over HTTP, if anywhere.
+	ingestAddress string
+	stopIngesting context.CancelFunc
+	ingestUpdates <-chan config.Update
+	// status is the latest status reported by the source, shown below the table.
+	status         string
+	loadingPage    bool
+	allPagesLoaded bool
+	rows           []config.Row
+	filteredRows   []config.Row
+	view           config.LogView
+	// mergesOrigins is set when the view reads from several files, whose rows are merged by time
+	// and shown along with the file they came from.
+	mergesOrigins bool
+	filters       []RowFilter
+	// lastShown and lastMatch are the indexes in rows of the last row that was shown and the last
+	// one that passed the filters, so that context rows can be added as more rows arrive.
+	lastShown, lastMatch int
+	// timeRange passes the rows within the view's time range, and is nil if the view has none.
+	timeRange       RowFilter
+	timeRangeDialog timerange.Model
+	search          search.Model
+	// query is the query bar, where filters can be typed as a query.
+	query textinput.Model
+	// queryErr is what was wrong with the query when it was last entered, if anything.
+	queryErr      *config.QueryError
+	queryErrValue string
+	// find is the find bar, where a term or /regex/ to jump between matches of is entered.
+	find        textinput.Model
+	findPattern *regexp.Regexp
+	findErr     string
+	// findMatches are the indexes in filteredRows of the rows that findPattern matches, in order.
+	findMatches []int
+	state       int
+	termWidth   int
+	termHeight  int
 }
 
 func newModel(logView config.LogView) *model {
 	modelInitTime := time.Now()
-	rows := logView.GetRows()
+	source := logView.GetSource()
+	rows, err := source.Rows()
+	if err != nil {
+		slog.Error(err.Error())
+		panic(err)
+	}
 	scanTime := time.Since(modelInitTime)
 	fmt.Fprintf(os.Stderr, " done in %d ms. %d rows found.\n", scanTime.Milliseconds(), len(rows))
+	if suggester, ok := source.(config.AttrSuggester); ok && len(logView.Attrs) == 0 {
+		logView.Attrs = suggester.SuggestedAttrs()
+	}
 
 	t := table.New(
-		table.WithFocused[string](true),
-		table.WithHeight[string](27),
+		table.WithFocused[config.Row](true),
+		table.WithHeight[config.Row](27),
+		table.WithBanners(markerBanner),
+		table.WithDimmed(isContextRow),
 	)
 
 	s := table.DefaultStyles()
@@ -65,16 +125,33 @@ func newModel(logView config.LogView) *model {
 	t.SetStyles(s)
 
 	m := &model{
-		table:   t,
-		state:   stateTable,
-		rows:    rows,
-		view:    logView,
-		filters: make([]RowFilter, 0),
-		schema:  schema.FromLogView(logView, 1, 1),
-		search:  search.FromLogView(logView, 40, 15),
+		table:           t,
+		state:           stateTable,
+		source:          source,
+		rows:            rows,
+		view:            logView,
+		mergesOrigins:   logView.ReadsManyFiles(),
+		filters:         make([]RowFilter, 0),
+		schema:          schema.FromLogView(logView, 1, 1),
+		search:          search.FromLogView(logView, 40, 15),
+		timeRangeDialog: timerange.FromLogView(logView),
 	}
+	if m.mergesOrigins {
+		m.rows = mergeByTime(m.rows, m.view.MergeAttr())
+	}
+	m.query = textinput.New()
+	m.query.Prompt = ": "
+	m.query.Placeholder = `level == "error" and (svc =~ "pay.*" or not msg contains "timeout")`
+	m.find = textinput.New()
+	m.find.Prompt = "find: "
+	m.find.Placeholder = "a term, or a /regex/"
 	m.updateColumns(logView.Attrs)
-	m.table.SetRows(m.rows)
+	if err := m.SetTimeRange(logView.TimeRange); err != nil {
+		m.status = err.Error()
+	}
+	if err := m.SetFilters(logView.Filters); err != nil {
+		m.status = err.Error()
+	}
 	return m
 }
 
@@ -84,28 +161,274 @@ func (rf RowFilter) Filter(s string) bool {
 	return rf(s)
 }
 
-func (m model) Init() tea.Cmd { return nil }
+func (m model) Init() tea.Cmd {
+	cmds := make([]tea.Cmd, 0, 2)
+	if m.view.Following() {
+		cmds = append(cmds, func() tea.Msg { return startFollowingMsg{} })
+	}
+	if m.ingestAddress != "" {
+		cmds = append(cmds, func() tea.Msg { return startIngestingMsg{} })
+	}
+	return tea.Batch(cmds...)
+}
+
+// startFollowingMsg asks the model to start following its source.
+type startFollowingMsg struct{}
+
+// startIngestingMsg asks the model to start accepting rows pushed over HTTP.
+type startIngestingMsg struct{}
+
+// rowsMsg carries rows that a followed source produced after the initial load.
+type rowsMsg struct {
+	update  config.Update
+	updates <-chan config.Update
+}
+
+// followStoppedMsg is sent when a follower, or the ingesting of rows, has stopped producing
+// updates.
+type followStoppedMsg struct {
+	updates <-chan config.Update
+}
+
+// startFollowing starts a goroutine that follows the source, and returns a command that waits for
+// its first update. It does nothing if the source can't be followed.
+func (m *model) startFollowing() tea.Cmd {
+	follower, ok := m.source.(config.Follower)
+	if !ok {
+		return nil
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	updates := make(chan config.Update)
+	m.stopFollowing, m.updates = cancel, updates
+	go func() {
+		defer close(updates)
+		if err := follower.Follow(ctx, updates); err != nil {
+			slog.Error("Following source failed", "error", err)
+			select {
+			case updates <- config.Update{Status: err.Error()}:
+			case <-ctx.Done():
+			}
+		}
+	}()
+	return waitForUpdate(updates)
+}
+
+// startIngesting starts accepting rows pushed over HTTP, and returns a command that waits for the
+// first of them. Ingested rows arrive as rowsMsgs, just like rows from a followed source.
+func (m *model) startIngesting() tea.Cmd {
+	ctx, cancel := context.WithCancel(context.Background())
+	updates := make(chan config.Update)
+	m.stopIngesting, m.ingestUpdates = cancel, updates
+	address := m.ingestAddress
+	go func() {
+		defer close(updates)
+		if err := config.Ingest(ctx, address, updates); err != nil {
+			slog.Error("Ingesting rows failed", "error", err)
+			select {
+			case updates <- config.Update{Status: err.Error()}:
+			case <-ctx.Done():
+			}
+		}
+	}()
+	return waitForUpdate(updates)
+}
+
+// quit stops following the source and ingesting rows, and quits.
+func (m *model) quit() tea.Cmd {
+	if m.stopFollowing != nil {
+		m.stopFollowing()
+	}
+	if m.stopIngesting != nil {
+		m.stopIngesting()
+	}
+	return tea.Quit
+}
+
+// restartFollowing stops following the source and starts over, which e.g. reruns a command.
+func (m *model) restartFollowing() tea.Cmd {
+	if _, ok := m.source.(config.Follower); !ok {
+		return nil
+	}
+	if m.stopFollowing != nil {
+		m.stopFollowing()
+	}
+	m.appendRows([]config.Row{config.MarkerRow("restarted")})
+	return m.startFollowing()
+}
+
+func waitForUpdate(updates <-chan config.Update) tea.Cmd {
+	return func() tea.Msg {
+		update, ok := <-updates
+		if !ok {
+			return followStoppedMsg{updates: updates}
+		}
+		return rowsMsg{update: update, updates: updates}
+	}
+}
+
+func (m *model) toggleFollow() tea.Cmd {
+	var cmd tea.Cmd
+	if m.stopFollowing != nil {
+		m.stopFollowing()
+		m.stopFollowing = nil
+	} else {
+		cmd = m.startFollowing()
+	}
+	following := m.stopFollowing != nil
+	if m.view.Options == nil {
+		m.view.Options = make(map[string]string)
+	}
+	m.view.Options["follow"] = strconv.FormatBool(following)
+	if m.sourceOverridden {
+		return cmd
+	}
+	view := config.TheConfig.GetActiveView()
+	view.Options = m.view.Options
+	config.TheConfig.Save()
+	return cmd
+}
+
+// appendRows adds rows to the model, filtering only the new rows. The cursor stays pinned to the
+// last row if it was there before, so that a followed source scrolls by itself.
+func (m *model) appendRows(rows []config.Row) {
+	atBottom := m.table.Cursor() >= len(m.filteredRows)-1
+	m.addRows(rows)
+	if atBottom {
+		m.table.GotoBottom()
+	}
+}
+
+// addRows adds rows to the model, filtering only the new rows.
+func (m *model) addRows(rows []config.Row) {
+	m.rows = append(m.rows, rows...)
+	m.filterRows(len(m.rows) - len(rows))
+	m.table.SetRows(m.filteredRows)
+}
+
+// pageLookahead is how close the cursor gets to the last loaded row before the next page is loaded
+// from a source that loads rows a page at a time.
+const pageLookahead = 100
+
+// pageMsg carries the next page of rows from a source that loads rows a page at a time.
+type pageMsg struct {
+	rows []config.Row
+	err  error
+}
+
+// loadNextPage returns a command that loads the next page of rows if the source has pages and the
+// cursor is getting close to the end of what has been loaded.
+func (m *model) loadNextPage() tea.Cmd {
+	pager, ok := m.source.(config.Pager)
+	if !ok || m.loadingPage || m.allPagesLoaded {
+		return nil
+	}
+	if m.table.Cursor() < len(m.filteredRows)-pageLookahead {
+		return nil
+	}
+	m.loadingPage = true
+	m.status = "Loading more rows…"
+	return func() tea.Msg {
+		rows, err := pager.NextPage()
+		return pageMsg{rows: rows, err: err}
+	}
+}
 
 func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	cmds := make([]tea.Cmd, 0)
 	var cmd tea.Cmd
+	switch msg := msg.(type) {
+	case startFollowingMsg:
+		return m, m.startFollowing()
+	case startIngestingMsg:
+		return m, m.startIngesting()
+	case rowsMsg:
+		if msg.update.Status != "" {
+			m.status = msg.update.Status
+		}
+		m.appendRows(msg.update.Rows)
+		return m, waitForUpdate(msg.updates)
+	case pageMsg:
+		m.loadingPage = false
+		switch {
+		case msg.err != nil:
+			m.status = msg.err.Error()
+		case len(msg.rows) == 0:
+			m.allPagesLoaded = true
+			m.status = fmt.Sprintf("All %d rows loaded", len(m.rows))
+		default:
+			m.status = ""
+			m.addRows(msg.rows)
+			// Rows on the new page may all have been filtered out
+			return m, m.loadNextPage()
+		}
+		return m, nil
+	case followStoppedMsg:
+		if msg.updates == m.ingestUpdates {
+			// Rows can no longer be pushed, most likely because the address was in use
+			m.ingestAddress, m.stopIngesting = "", nil
+			return m, nil
+		}
+		// A follower that was replaced by a restart stops too, but that one is no longer of interest
+		if msg.updates == m.updates && m.stopFollowing != nil {
+			m.stopFollowing()
+			m.stopFollowing = nil
+		}
+		return m, nil
+	}
 	switch m.state {
 	case stateTable:
 		switch msg := msg.(type) {
 		case tea.KeyMsg:
 			switch msg.String() {
 			case " ":
-				m.state = stateZoomRow
+				// Sources that stream have no rows until the first ones arrive
+				if len(m.filteredRows) > 0 {
+					m.state = stateZoomRow
+				}
 			case "s":
 				m.state = stateSchema
 			case "/":
 				m.state = stateSearch
+			case ":":
+				m.state = stateQuery
+				m.queryErr = nil
+				m.query.SetValue(config.FormatQuery(m.view.Filters))
+				m.query.CursorEnd()
+				return m, m.query.Focus()
+			case "?":
+				m.state = stateFind
+				m.findErr = ""
+				m.find.CursorEnd()
+				return m, m.find.Focus()
+			case "n":
+				m.findNext(m.table.Cursor(), true)
+			case "N":
+				m.findNext(m.table.Cursor(), false)
+			case "=":
+				m.quickFilter(config.Equal)
+			case "!":
+				m.quickFilter(config.NotEqual)
+			case "T":
+				m.filterByTrace()
+			case "+":
+				m.setContext(m.view.Context + 1)
+			case "-":
+				m.setContext(m.view.Context - 1)
+			case "t":
+				m.state = stateTimeRange
+				m.timeRangeDialog.SetLogView(m.view)
+				m.timeRangeDialog.SetRange(m.view.TimeRange)
+				return m, nil
+			case "f":
+				cmds = append(cmds, m.toggleFollow())
+			case "r":
+				cmds = append(cmds, m.restartFollowing())
 			case "q", "ctrl+c":
-				return m, tea.Quit
+				return m, m.quit()
 			}
 		}
 		m.table, cmd = m.table.Update(msg)
-		cmds = append(cmds, cmd)
+		cmds = append(cmds, cmd, m.loadNextPage())
 	case stateZoomRow:
 		switch msg := msg.(type) {
 		case tea.KeyMsg:
@@ -121,6 +444,13 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			return m, nil
 		case schema.UpdatedSchemaMsg:
 			m.updateColumns(msg.Attributes)
+			// The time range and filters look their attributes up by name, so rebuild them
+			if err := m.SetTimeRange(m.view.TimeRange); err != nil {
+				m.status = err.Error()
+			}
+			if err := m.SetFilters(m.view.Filters); err != nil {
+				m.status = err.Error()
+			}
 		}
 		m.schema, cmd = m.schema.Update(msg)
 		cmds = append(cmds, cmd)
@@ -130,20 +460,83 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			m.state = stateTable
 			return m, nil
 		case search.UpdatedFiltersMsg:
-			m.SetFilters(msg.Filters)
+			m.saveFilters(msg.Filters)
 		}
 		m.search, cmd = m.search.Update(msg)
 		cmds = append(cmds, cmd)
+	case stateQuery:
+		switch msg := msg.(type) {
+		case tea.KeyMsg:
+			switch msg.String() {
+			case "esc":
+				m.state = stateTable
+				m.query.Blur()
+				return m, nil
+			case "enter":
+				filters, err := config.ParseQuery(m.query.Value(), m.view)
+				if err != nil {
+					if !errors.As(err, &m.queryErr) {
+						m.queryErr = &config.QueryError{Msg: err.Error()}
+					}
+					m.queryErrValue = m.query.Value()
+					return m, nil
+				}
+				m.state = stateTable
+				m.query.Blur()
+				m.saveFilters(filters)
+				m.search.SetFilters(filters)
+				return m, nil
+			}
+		}
+		m.query, cmd = m.query.Update(msg)
+		cmds = append(cmds, cmd)
+	case stateTimeRange:
+		switch msg := msg.(type) {
+		case timerange.Close:
+			m.state = stateTable
+			return m, nil
+		case timerange.UpdatedRangeMsg:
+			m.state = stateTable
+			m.saveTimeRange(msg.Range)
+			return m, nil
+		}
+		m.timeRangeDialog, cmd = m.timeRangeDialog.Update(msg)
+		cmds = append(cmds, cmd)
+	case stateFind:
+		switch msg := msg.(type) {
+		case tea.KeyMsg:
+			switch msg.String() {
+			case "esc":
+				m.state = stateTable
+				m.find.Blur()
+				return m, nil
+			case "enter":
+				pattern, err := parseFindTerm(m.find.Value())
+				if err != nil {
+					m.findErr = err.Error()
+					return m, nil
+				}
+				m.state = stateTable
+				m.find.Blur()
+				m.setFindPattern(pattern)
+				// The selected row counts as the next match, if it is one
+				m.findNext(m.table.Cursor()-1, true)
+				return m, m.loadNextPage()
+			}
+		}
+		m.find, cmd = m.find.Update(msg)
+		cmds = append(cmds, cmd)
 	}
 	switch msg := msg.(type) {
 	case tea.KeyMsg:
 		switch msg.String() {
 		case "ctrl+c":
-			return m, tea.Quit
+			return m, m.quit()
 		}
 
 	case tea.WindowSizeMsg:
-		m.table, cmd = m.table.Update(msg)
+		// Leave room for the status line
+		m.table, cmd = m.table.Update(tea.WindowSizeMsg{Width: msg.Width, Height: msg.Height - 1})
 		cmds = append(cmds, cmd)
 		m.schema, cmd = m.schema.Update(msg)
 		cmds = append(cmds, cmd)
@@ -153,11 +546,20 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 }
 
 func (m *model) updateColumns(attrs []config.Attribute) {
-	columns := make([]table.ColumnSpec[string], len(attrs))
+	columns := make([]table.ColumnSpec[config.Row], len(attrs), len(attrs)+1)
+	hasOriginColumn := false
 	for i, c := range attrs {
 		columns[i] = ColumnFromConfig(c)
+		hasOriginColumn = hasOriginColumn || contains(c.Selectors, originSelector)
+	}
+	if m.mergesOrigins && !hasOriginColumn {
+		origin := config.Attribute{Name: "Origin", Width: 20, Selectors: []string{originSelector}}
+		columns = append([]table.ColumnSpec[config.Row]{ColumnFromConfig(origin)}, columns...)
 	}
 	m.table.SetColumns(columns)
+	m.updateFindMatches()
+	m.view.Attrs = attrs
+	m.search.SetAttrs(attrs)
 	view := config.TheConfig.GetActiveView()
 	view.Attrs = attrs
 	config.TheConfig.Save()
@@ -173,14 +575,18 @@ func (m model) View() string {
 	switch m.state {
 
 	case stateTable:
-		return baseStyle.Render(m.table.View())
+		return baseStyle.Render(m.table.View()) + "\n" + m.statusLine()
 
 	case stateZoomRow:
 		rawRow := m.table.SelectedRow()
+		if rawRow.Marker {
+			return baseStyle.Width(m.termWidth - 2).Render(rawRow.Line)
+		}
 		var row map[string]interface{}
-		err := json.Unmarshal([]byte(rawRow), &row)
+		err := json.Unmarshal([]byte(rawRow.Line), &row)
 		if err != nil {
-			return "Invalid"
+			// Not JSON, like a multiline record of plain text, so show it as it is
+			return baseStyle.Width(m.termWidth - 2).Render(rawRow.Line)
 		}
 		rendered, err := json.MarshalIndent(row, "", "    ")
 		if err != nil {
@@ -194,53 +600,482 @@ func (m model) View() string {
 	case stateSearch:
 		return baseStyle.Render(m.search.View())
 
+	case stateQuery:
+		return baseStyle.Render(m.table.View()) + "\n" + m.queryLine()
+
+	case stateTimeRange:
+		return baseStyle.Render(m.timeRangeDialog.View())
+
+	case stateFind:
+		findLine := m.find.View()
+		if m.findErr != "" {
+			findLine += "  " + queryErrorStyle.Render(m.findErr)
+		}
+		return baseStyle.Render(m.table.View()) + "\n" + findLine
+
 	default:
 		panic("Unknown state")
 	}
 }
 
-func (m *model) SetFilters(filters []config.Filter) {
-	rowFilters := make([]RowFilter, len(filters))
-	for i, filter := range filters {
-		filter := filter
-		rowFilter := func(row string) bool {
-			return strings.Contains(row, filter.Term)
+// statusLine tells whether the source is being followed, what time range the rows are limited to,
+// how much context is shown, how the selected row relates to what is being found and whether rows
+// can be pushed into the session, along with what the source last reported.
+func (m model) statusLine() string {
+	parts := make([]string, 0, 6)
+	if m.stopFollowing != nil {
+		parts = append(parts, "following")
+	}
+	if tr := m.view.TimeRange; tr != nil {
+		from, to := orDefault(tr.From, "start"), orDefault(tr.To, "end")
+		parts = append(parts, fmt.Sprintf("from %s to %s", from, to))
+	}
+	if m.view.Context > 0 {
+		parts = append(parts, fmt.Sprintf("%d rows of context", m.view.Context))
+	}
+	if m.findPattern != nil {
+		parts = append(parts, m.findStatus())
+	}
+	if m.ingestAddress != "" {
+		parts = append(parts, "ingesting on "+m.ingestAddress)
+	}
+	if m.status != "" {
+		parts = append(parts, m.status)
+	}
+	return statusStyle.Width(m.termWidth).Render(strings.Join(parts, " · "))
+}
+
+// queryLine renders the query bar. If the query that was entered is wrong, the wrong part of it is
+// highlighted until the query is changed.
+func (m model) queryLine() string {
+	value := m.query.Value()
+	if m.queryErr == nil || m.queryErrValue != value {
+		return m.query.View()
+	}
+	pos, end := min(m.queryErr.Pos, len(value)), min(m.queryErr.End, len(value))
+	wrong := value[pos:end]
+	if wrong == "" {
+		wrong = " "
+	}
+	return m.query.Prompt + value[:pos] + queryErrorStyle.Underline(true).Render(wrong) + value[end:] +
+		"  " + queryErrorStyle.Render(m.queryErr.Msg)
+}
+
+// saveFilters filters the rows by filters, and saves them as the view's filters.
+func (m *model) saveFilters(filters []config.Filter) {
+	m.status = ""
+	if err := m.SetFilters(filters); err != nil {
+		m.status = err.Error()
+	}
+	m.view.Filters = filters
+	view := config.TheConfig.GetActiveView()
+	view.Filters = filters
+	config.TheConfig.Save()
+}
+
+// saveTimeRange limits the rows to tr, or lifts the limit if tr is nil, and saves it as the view's
+// time range.
+func (m *model) saveTimeRange(tr *config.TimeRange) {
+	m.status = ""
+	if err := m.SetTimeRange(tr); err != nil {
+		m.status = err.Error()
+	}
+	m.view.TimeRange = tr
+	view := config.TheConfig.GetActiveView()
+	view.TimeRange = tr
+	config.TheConfig.Save()
+}
+
+// quickFilter adds a filter that compares the attribute of the selected column to its value in the
+// selected row, like Level == "error".
+func (m *model) quickFilter(op config.FilterOp) {
+	if len(m.filteredRows) == 0 || m.table.SelectedRow().Marker {
+		return
+	}
+	columns := m.table.Columns()
+	if m.table.ColumnCursor() >= len(columns) {
+		return
+	}
+	col, ok := columns[m.table.ColumnCursor()].(*Column)
+	if !ok || contains(col.attr.Selectors, originSelector) {
+		m.status = "Can't filter by " + columns[m.table.ColumnCursor()].Title()
+		return
+	}
+	filters := append(append([]config.Filter{}, m.view.Filters...), m.cellFilter(col.attr, op))
+	m.applyQuickFilters(filters)
+}
+
+// filterByTrace replaces the filters with one that shows the rows that have the same trace id as
+// the selected row, which is extracted by the view's attribute named like trace_id or traceId.
+func (m *model) filterByTrace() {
+	if len(m.filteredRows) == 0 || m.table.SelectedRow().Marker {
+		return
+	}
+	for _, attr := range m.view.Attrs {
+		name := strings.NewReplacer("_", "", "-", "", ".", "", " ", "").Replace(attr.Name)
+		if strings.EqualFold(name, "traceid") {
+			m.applyQuickFilters([]config.Filter{m.cellFilter(attr, config.Equal)})
+			return
 		}
-		rowFilters[i] = rowFilter
+	}
+	m.status = "No trace_id attribute"
+}
+
+// cellFilter returns a filter that compares attr to its value in the selected row.
+func (m *model) cellFilter(attr config.Attribute, op config.FilterOp) config.Filter {
+	value := valueGetterFromSelectors(attr.Selectors, "", nil)(m.table.SelectedRow().Line)
+	return config.Filter{Term: value, Operator: op, Attr: &attr}
+}
+
+// applyQuickFilters saves filters as the view's filters, keeping the selected row selected if it
+// still passes them.
+func (m *model) applyQuickFilters(filters []config.Filter) {
+	selected := m.table.SelectedRow()
+	m.saveFilters(filters)
+	m.search.SetFilters(filters)
+	if m.status == "" {
+		m.status = "Filtered by " + config.FormatQuery(filters[len(filters)-1:])
+	}
+	for i, row := range m.filteredRows {
+		if row.Line == selected.Line && row.Origin == selected.Origin {
+			m.moveCursorTo(i)
+			break
+		}
+	}
+}
+
+// moveCursorTo selects row i of the table, scrolling it into view.
+func (m *model) moveCursorTo(i int) {
+	if cursor := m.table.Cursor(); i > cursor {
+		m.table.MoveDown(i - cursor)
+	} else {
+		m.table.MoveUp(cursor - i)
+	}
+}
+
+// SetTimeRange limits the rows to those within tr, or lifts the limit if tr is nil. Relative ends,
+// like -15m, are relative to now. If tr is invalid, the rows aren't limited and the error is
+// returned.
+func (m *model) SetTimeRange(tr *config.TimeRange) error {
+	var err error
+	m.timeRange = nil
+	if tr != nil {
+		if m.timeRange, err = m.timeRangeFilter(*tr); err != nil {
+			slog.Error("Invalid time range", "error", err)
+		}
+	}
+	m.updateFilteredRows()
+	m.table.SetRows(m.filteredRows)
+	return err
+}
+
+// timeRangeFilter returns a RowFilter that passes the rows whose time attribute is within tr.
+func (m *model) timeRangeFilter(tr config.TimeRange) (RowFilter, error) {
+	attr, err := tr.Attribute(m.view)
+	if err != nil {
+		return nil, err
+	}
+	within, err := tr.Matcher(time.Now())
+	if err != nil {
+		return nil, err
+	}
+	value := valueGetterFromSelectors(attr.Selectors, "", nil)
+	return func(row string) bool {
+		return within(value(row))
+	}, nil
+}
+
+// SetFilters filters the rows by filters, all of which a row must pass to be shown. A filter that
+// is invalid, like one with a malformed regex, is left out, and the first such error is returned.
+func (m *model) SetFilters(filters []config.Filter) error {
+	var firstErr error
+	rowFilters := make([]RowFilter, 0, len(filters))
+	for _, filter := range filters {
+		rowFilter, err := m.rowFilter(filter)
+		if err != nil {
+			slog.Error("Invalid filter", "error", err)
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+		rowFilters = append(rowFilters, rowFilter)
 	}
 	m.filters = rowFilters
 	m.updateFilteredRows()
 	m.table.SetRows(m.filteredRows)
+	return firstErr
+}
+
+// rowFilter returns a RowFilter that applies filter to the value its attribute extracts from a row,
+// or to the whole row if the filter has no attribute. The attribute is looked up by name in the
+// view, so that filters follow changes to the attribute's selectors. Groups of filters are combined
+// as they say.
+func (m *model) rowFilter(filter config.Filter) (RowFilter, error) {
+	rowFilter, err := m.comparisonOrGroup(filter)
+	if err != nil || !filter.Not {
+		return rowFilter, err
+	}
+	return func(row string) bool { return !rowFilter(row) }, nil
+}
+
+func (m *model) comparisonOrGroup(filter config.Filter) (RowFilter, error) {
+	if filter.IsGroup() {
+		anyOf := len(filter.Any) > 0
+		members := filter.All
+		if anyOf {
+			members = filter.Any
+		}
+		rowFilters := make([]RowFilter, len(members))
+		for i, member := range members {
+			var err error
+			if rowFilters[i], err = m.rowFilter(member); err != nil {
+				return nil, err
+			}
+		}
+		return func(row string) bool {
+			for _, rowFilter := range rowFilters {
+				if rowFilter(row) == anyOf {
+					return anyOf
+				}
+			}
+			return !anyOf
+		}, nil
+	}
+	if filter.Attr != nil {
+		if attr, err := m.view.GetAttributeWithName(filter.Attr.Name); err == nil {
+			filter.Attr = attr
+		}
+	}
+	matches, err := filter.Matcher()
+	if err != nil {
+		return nil, err
+	}
+	if filter.Attr == nil {
+		return matches, nil
+	}
+	value := valueGetterFromSelectors(filter.Attr.Selectors, "", nil)
+	return func(row string) bool {
+		return matches(value(row))
+	}, nil
 }
 
 func (m *model) updateFilteredRows() {
-	m.filteredRows = make([]string, 0, len(m.rows)/10)
-	for _, row := range m.rows {
-		include := true
-		for _, filter := range m.filters {
-			if !filter(row) {
-				include = false
-				break
+	m.filteredRows = make([]config.Row, 0, len(m.rows)/10)
+	m.findMatches = nil
+	m.lastShown, m.lastMatch = -1, -1
+	m.filterRows(0)
+}
+
+// contextSeparator is shown between groups of rows that aren't next to each other when rows are
+// shown with context.
+var contextSeparator = config.Row{Line: "⋯", Marker: true, Context: true}
+
+// filterRows adds the rows in rows[from:] that pass the filters to filteredRows. If the view has
+// context, the rows around them are added too, as context rows, with separators between groups
+// of rows that aren't next to each other.
+//
+// Rows outside of the time range are never shown, not even as context.
+func (m *model) filterRows(from int) {
+	around := m.view.Context
+	for i := from; i < len(m.rows); i++ {
+		row := m.rows[i]
+		switch {
+		case row.Marker:
+			m.showRow(row)
+			m.lastShown = i
+		case m.includeRow(row):
+			start := max(i-around, m.lastShown+1)
+			if around > 0 && m.lastShown >= 0 && start > m.lastShown+1 {
+				m.showRow(contextSeparator)
+			}
+			for _, before := range m.rows[start:i] {
+				if m.inTimeRange(before) {
+					before.Context = true
+					m.showRow(before)
+				}
+			}
+			m.showRow(row)
+			m.lastShown, m.lastMatch = i, i
+		case m.lastMatch >= 0 && i == m.lastShown+1 && i <= m.lastMatch+around:
+			if m.inTimeRange(row) {
+				row.Context = true
+				m.showRow(row)
 			}
+			m.lastShown = i
 		}
-		if include {
-			m.filteredRows = append(m.filteredRows, row)
+	}
+}
+
+// showRow adds row to filteredRows.
+func (m *model) showRow(row config.Row) {
+	if m.findsRow(row) {
+		m.findMatches = append(m.findMatches, len(m.filteredRows))
+	}
+	m.filteredRows = append(m.filteredRows, row)
+}
+
+// setContext shows n rows of context around each row that passes the filters, and saves it as the
+// view's context.
+func (m *model) setContext(n int) {
+	m.view.Context = max(n, 0)
+	m.updateFilteredRows()
+	m.table.SetRows(m.filteredRows)
+	view := config.TheConfig.GetActiveView()
+	view.Context = m.view.Context
+	config.TheConfig.Save()
+}
+
+// parseFindTerm parses what was entered in the find bar. A term in slashes, like /\d+ms/, is a
+// regular expression; anything else is found as it is, ignoring case unless it has upper case
+// letters. An empty term finds nothing, and gives a nil pattern.
+func parseFindTerm(term string) (*regexp.Regexp, error) {
+	switch {
+	case term == "":
+		return nil, nil
+	case len(term) >= 2 && strings.HasPrefix(term, "/") && strings.HasSuffix(term, "/"):
+		pattern, err := regexp.Compile(term[1 : len(term)-1])
+		if err != nil {
+			return nil, fmt.Errorf("invalid regex %s: %w", term, err)
+		}
+		return pattern, nil
+	case strings.ToLower(term) == term:
+		return regexp.MustCompile("(?i)" + regexp.QuoteMeta(term)), nil
+	default:
+		return regexp.MustCompile(regexp.QuoteMeta(term)), nil
+	}
+}
+
+// setFindPattern finds the rows that pattern matches, and highlights the matches. A nil pattern
+// stops finding anything.
+func (m *model) setFindPattern(pattern *regexp.Regexp) {
+	m.findPattern = pattern
+	if pattern == nil {
+		m.table.SetHighlight(nil)
+	} else {
+		m.table.SetHighlight(func(s string) [][]int { return pattern.FindAllStringIndex(s, -1) })
+	}
+	m.updateFindMatches()
+}
+
+func (m *model) updateFindMatches() {
+	m.findMatches = nil
+	for i, row := range m.filteredRows {
+		if m.findsRow(row) {
+			m.findMatches = append(m.findMatches, i)
+		}
+	}
+}
+
+// findsRow reports whether the find pattern matches what any column shows of row.
+func (m *model) findsRow(row config.Row) bool {
+	if m.findPattern == nil || row.Marker {
+		return false
+	}
+	for _, col := range m.table.Columns() {
+		if m.findPattern.MatchString(col.GetValue(row)) {
+			return true
+		}
+	}
+	return false
+}
+
+// findNext moves the cursor to the first row after from that the find pattern matches, or the last
+// one before it if forward isn't set, wrapping around at the ends.
+func (m *model) findNext(from int, forward bool) {
+	if len(m.findMatches) == 0 {
+		return
+	}
+	var next int
+	if forward {
+		next = sort.SearchInts(m.findMatches, from+1) % len(m.findMatches)
+	} else {
+		next = sort.SearchInts(m.findMatches, from) - 1
+		if next < 0 {
+			next = len(m.findMatches) - 1
 		}
 	}
+	m.moveCursorTo(m.findMatches[next])
+}
+
+// findStatus tells which of the matches the selected row is, like "match 3/41", or how many
+// matches there are if it isn't one.
+func (m model) findStatus() string {
+	i := sort.SearchInts(m.findMatches, m.table.Cursor())
+	switch {
+	case len(m.findMatches) == 0:
+		return "no matches"
+	case i < len(m.findMatches) && m.findMatches[i] == m.table.Cursor():
+		return fmt.Sprintf("match %d/%d", i+1, len(m.findMatches))
+	default:
+		return fmt.Sprintf("%d matches", len(m.findMatches))
+	}
+}
+
+func (m *model) includeRow(row config.Row) bool {
+	if row.Marker {
+		return true
+	}
+	// The time range is checked first, as it tends to rule out most rows and is cheap to check
+	if !m.inTimeRange(row) {
+		return false
+	}
+	for _, filter := range m.filters {
+		if !filter(row.Line) {
+			return false
+		}
+	}
+	return true
+}
+
+func (m *model) inTimeRange(row config.Row) bool {
+	return m.timeRange == nil || m.timeRange(row.Line)
+}
+
+// markerBanner renders marker rows, like where a followed file was rotated, as a line across the
+// table. Separators between groups of context rows are rendered as they are.
+func markerBanner(row config.Row) (string, bool) {
+	if !row.Marker {
+		return "", false
+	}
+	if row.Context {
+		return row.Line, true
+	}
+	return "── " + row.Line + " ──", true
+}
+
+func isContextRow(row config.Row) bool {
+	return row.Context
 }
 
 type Column struct {
 	title       string
 	width       int
-	valueGetter func(string) string
+	valueGetter func(config.Row) string
+	// attr is the attribute that the column shows.
+	attr config.Attribute
 }
 
+// originSelector is a selector that yields where a row was read from, e.g. the file name when a view
+// reads from several files.
+const originSelector = "origin()"
+
 func ColumnFromConfig(c config.Attribute) *Column {
-	return &Column{
-		title:       c.Name,
-		width:       c.Width,
-		valueGetter: valueGetterFromSelectors(c.Selectors, c.Type, c.Format),
+	valueGetter := valueGetterFromSelectors(c.Selectors, c.Type, c.Format)
+	column := &Column{
+		title: c.Name,
+		width: c.Width,
+		valueGetter: func(row config.Row) string {
+			return valueGetter(row.Line)
+		},
+		attr: c,
 	}
+	if contains(c.Selectors, originSelector) {
+		column.valueGetter = func(row config.Row) string {
+			return row.Origin
+		}
+	}
+	return column
 }
 
 func (c *Column) Title() string {
@@ -255,8 +1090,8 @@ func (c *Column) SetWidth(width int) {
 	c.width = width
 }
 
-func (c *Column) GetValue(s string) string {
-	return c.valueGetter(s)
+func (c *Column) GetValue(row config.Row) string {
+	return c.valueGetter(row)
 }
 
 func identity(s string) string {
@@ -265,7 +1100,7 @@ func identity(s string) string {
 
 // valueGetterFromSelectors returns a function that can be used to get column values.
 //
-// For now it is useful for getting values from JSON data (including nested JSON data).
+// It is useful for getting values from JSON data (including nested JSON data), and from plain text.
 //
 // Example:
 //
@@ -283,6 +1118,18 @@ func identity(s string) string {
 //
 //	valueGetterFromSelectors([]string{"json(data)|json(barcode)"}, "", nil)
 //
+// Plain text can be picked apart with a regex(pattern, group) step, which yields the named (or
+// numbered) group of a regular expression. It can be piped like any other step:
+//
+//	valueGetterFromSelectors([]string{"json(msg) | regex((?P<ms>\d+)ms, ms)"}, "", nil)
+//
+// Patterns are compiled once, when the getter is created. Likewise, a logfmt(key) step yields the
+// value of key in a logfmt line like `level=info msg="hello world"`, and a pattern(name, field)
+// step matches a grok-style pattern and yields one of the fields it captures. Patterns for common
+// access log formats are built in (see grokPatterns), and more can be defined in config.toml:
+//
+//	valueGetterFromSelectors([]string{"pattern(nginx_combined, status)"}, "", nil)
+//
 // In cases where your log source has imperfectly structured data, you can use the fact that the
 // 'selectors' parameter is a slice. This means that you can provide multiple selectors and the
 // first one to yield a non-empty result is returned.
@@ -293,19 +1140,15 @@ func valueGetterFromSelectors(selectors []string, typ string, format *string) fu
 			getters[i] = identity
 			continue
 		}
-		partialSelectors := strings.Split(selector, "|")
+		steps, err := parseSelector(selector)
+		if err != nil {
+			slog.Error(err.Error())
+			getters[i] = func(string) string { return "Invalid" }
+			continue
+		}
 		getters[i] = func(s string) string {
-			for _, sel := range partialSelectors {
-				sel = strings.TrimSpace(sel)
-				if strings.HasPrefix(sel, "json(") {
-					// set jsonPath to whatever is inside the parentheses of
-					// 'json(...)'
-					jsonPath := sel[5 : len(sel)-1]
-					if jsonPath == "." {
-						continue
-					}
-					s = gjson.Get(s, jsonPath).String()
-				}
+			for _, step := range steps {
+				s = step(s)
 			}
 			switch typ {
 			case "time":
@@ -1,9 +1,18 @@
 package main
 
 import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/tidwall/gjson"
 	"github.com/torarvid/gloglog/config"
+	"github.com/torarvid/gloglog/schema"
+	"github.com/torarvid/gloglog/search"
+	"github.com/torarvid/gloglog/table"
 )
 
 func TestFilterSimple(t *testing.T) {
@@ -11,7 +20,7 @@ func TestFilterSimple(t *testing.T) {
 		`{"level":"info","msg":"hello world","time":"2020-01-01T00:00:00Z"}`,
 		`{"level":"info","msg":"goodbye world","time":"2020-01-01T00:00:00Z"}`,
 	}
-	mainModel := model{rows: testRows}
+	mainModel := model{rows: rowsFrom(testRows...)}
 
 	// no filters should yield all rows
 	mainModel.SetFilters([]config.Filter{})
@@ -32,6 +41,32 @@ func TestFilterSimple(t *testing.T) {
 	}
 }
 
+func TestFilterAttribute(t *testing.T) {
+	testRows := []string{
+		`{"level":"info","msg":"took 40ms"}`,
+		`{"level":"error","msg":"took 152ms"}`,
+		`{"level":"info","msg":"took 9ms, error ignored"}`,
+	}
+	level := config.Attribute{Name: "Level", Selectors: []string{"json(level)"}}
+	took := config.Attribute{Name: "Took", Selectors: []string{"json(msg) | regex((\\d+)ms)"}}
+	mainModel := model{rows: rowsFrom(testRows...), view: config.LogView{Attrs: []config.Attribute{level, took}}}
+
+	mainModel.SetFilters([]config.Filter{{Term: "error", Operator: config.Equal, Attr: &level}})
+	if len(mainModel.filteredRows) != 1 {
+		t.Errorf("Expected 1 row, got %d", len(mainModel.filteredRows))
+	}
+
+	mainModel.SetFilters([]config.Filter{{Term: "10", Operator: config.GreaterThan, Attr: &took}})
+	if len(mainModel.filteredRows) != 2 {
+		t.Errorf("Expected 2 rows, got %d", len(mainModel.filteredRows))
+	}
+
+	err := mainModel.SetFilters([]config.Filter{{Term: "[", Operator: config.RegexEqual}, {Term: "error"}})
+	if err == nil || len(mainModel.filteredRows) != 2 {
+		t.Errorf("Expected an error and 2 rows, got %v and %d", err, len(mainModel.filteredRows))
+	}
+}
+
 func TestValueFromSelectors(t *testing.T) {
 	testRow := `{"level":"info","msg":"hello world","time":"2020-01-01T00:00:00Z"}`
 
@@ -71,3 +106,365 @@ func TestValueFromSelectors(t *testing.T) {
 		t.Errorf("Expected value to be 'info', got '%s'", value)
 	}
 }
+
+func TestRegexSelector(t *testing.T) {
+	testRow := `{"msg":"GET /users took 152ms (a|b)"}`
+	for selector, expected := range map[string]string{
+		`json(msg) | regex((?P<ms>\d+)ms, ms)`:      "152",
+		`json(msg) | regex(^(\w+) (\S+), 2)`:        "/users",
+		`json(msg) | regex(took \d+)`:               "took 152",
+		`json(msg) | regex(\((a|b)\|b\))`:           "a",
+		`json(msg) | regex(\d{1,2}) | regex(5)`:     "5",
+		`json(msg) | regex(nothing (?P<x>here), x)`: "",
+		`json(msg) | regex((?P<ms>\d+)ms, nope)`:    "Invalid",
+		`regex([, 1)`:                               "Invalid",
+	} {
+		value := valueGetterFromSelectors([]string{selector}, "", nil)(testRow)
+		if value != expected {
+			t.Errorf("%s: expected '%s', got '%s'", selector, expected, value)
+		}
+	}
+}
+
+func TestLogfmtSelector(t *testing.T) {
+	testRow := `ts=2024-01-02T03:04:05Z level=info msg="say \"hi\"\tthere" debug path=/a=b empty=`
+	for selector, expected := range map[string]string{
+		"logfmt(level)":             "info",
+		"logfmt(msg)":               "say \"hi\"\tthere",
+		"logfmt(debug)":             "true",
+		"logfmt(path)":              "/a=b",
+		"logfmt(empty)":             "",
+		"logfmt(missing)":           "",
+		"logfmt(msg) | regex(\\w+)": "say",
+	} {
+		value := valueGetterFromSelectors([]string{selector}, "", nil)(testRow)
+		if value != expected {
+			t.Errorf("%s: expected '%s', got '%s'", selector, expected, value)
+		}
+	}
+	value := valueGetterFromSelectors([]string{"logfmt(ts)"}, "time", nil)(testRow)
+	if value != "Jan  2 03:04:05.000" {
+		t.Errorf("Expected a formatted time, got '%s'", value)
+	}
+}
+
+func TestPatternSelector(t *testing.T) {
+	config.TheConfig = &config.Config{Patterns: map[string]string{
+		"myapp": `%{TIMESTAMP_ISO8601:time} \[%{LOGLEVEL:level}\] %{GREEDYDATA:message}`,
+		"loop":  `%{loop}`,
+	}}
+	defer func() { config.TheConfig = nil }()
+
+	combined := `203.0.113.9 - frank [10/Oct/2000:13:55:36 -0700] "GET /apache_pb.gif HTTP/1.0" 200 2326 ` +
+		`"http://www.example.com/start.html" "Mozilla/4.08 [en] (Win98; I ;Nav)"`
+	alb := `https 2018-07-02T22:23:00.186641Z app/my-loadbalancer/50dc6c495c0c9188 192.168.131.39:2817 ` +
+		`10.0.0.1:80 0.086 0.048 0.037 200 200 0 57 "GET https://www.example.com:443/ HTTP/1.1" ` +
+		`"curl/7.46.0" ECDHE-RSA-AES128-GCM-SHA256 TLSv1.2 ` +
+		`arn:aws:elasticloadbalancing:us-east-2:123456789012:targetgroup/my-targets/73e2d6bc24d8a067 ` +
+		`"Root=1-58337281-1d84f3d73c47ec4e58577259" "www.example.com" "-" 0 2018-07-02T22:22:48.364000Z ` +
+		`"authenticate,forward" "-" "-"`
+	cloudfront := "2019-12-04\t21:02:31\tLAX1\t392\t192.0.2.100\tGET\td111111abcdef8.cloudfront.net\t" +
+		"/index.html\t200\t-\tMozilla/5.0\t-\t-\tHit\tSOX4xwn4XV6Q4rgb7XiVGOHms\t" +
+		"d111111abcdef8.cloudfront.net\thttps\t23\t0.001\t-\tTLSv1.2"
+	for _, test := range []struct{ row, selector, expected string }{
+		{combined, "pattern(nginx_combined, status)", "200"},
+		{combined, "pattern(apache_combined, path)", "/apache_pb.gif"},
+		{combined, "pattern(apache_common, auth)", "frank"},
+		{combined, "pattern(apache_combined, user_agent)", "Mozilla/4.08 [en] (Win98; I ;Nav)"},
+		{alb, "pattern(aws_alb, target_processing_time)", "0.048"},
+		{alb, "pattern(aws_alb, trace_id)", "Root=1-58337281-1d84f3d73c47ec4e58577259"},
+		{cloudfront, "pattern(cloudfront, result_type)", "Hit"},
+		{`{"log":"2024-01-02 03:04:05 [WARN] disk full"}`, "json(log) | pattern(myapp, level)", "WARN"},
+		{"not a match", "pattern(myapp, level)", ""},
+		{combined, "pattern(nginx_combined, nope)", "Invalid"},
+		{combined, "pattern(nope, status)", "Invalid"},
+		{combined, "pattern(loop, status)", "Invalid"},
+	} {
+		value := valueGetterFromSelectors([]string{test.selector}, "", nil)(test.row)
+		if value != test.expected {
+			t.Errorf("%s: expected '%s', got '%s'", test.selector, test.expected, value)
+		}
+	}
+}
+
+func TestTimeRange(t *testing.T) {
+	testRows := []string{
+		`{"time":"2020-01-01T14:01:00Z","msg":"before"}`,
+		`{"time":"2020-01-01T14:05:00Z","msg":"during"}`,
+		`{"time":"2020-01-01T14:11:00Z","msg":"after"}`,
+	}
+	timeAttr := config.Attribute{Name: "Time", Selectors: []string{"json(time)"}, Type: "time"}
+	mainModel := model{rows: rowsFrom(testRows...), view: config.LogView{Attrs: []config.Attribute{timeAttr}}}
+
+	tr := config.TimeRange{From: "2020-01-01T14:02:00Z", To: "2020-01-01T14:10:00Z"}
+	if err := mainModel.SetTimeRange(&tr); err != nil {
+		t.Fatal(err)
+	}
+	mainModel.SetFilters([]config.Filter{{Term: "during"}})
+	if len(mainModel.filteredRows) != 1 {
+		t.Errorf("Expected 1 row, got %d", len(mainModel.filteredRows))
+	}
+
+	mainModel.SetFilters(nil)
+	err := mainModel.SetTimeRange(&config.TimeRange{Attr: "Time", From: "whenever"})
+	if err == nil || len(mainModel.filteredRows) != 3 {
+		t.Errorf("Expected an error and 3 rows, got %v and %d", err, len(mainModel.filteredRows))
+	}
+}
+
+func TestFind(t *testing.T) {
+	testRows := []string{
+		`{"msg":"Took 40ms"}`,
+		`{"msg":"hello"}`,
+		`{"msg":"took 152ms"}`,
+	}
+	msg := config.Attribute{Name: "Msg", Selectors: []string{"json(msg)"}}
+	mainModel := model{rows: rowsFrom(testRows...)}
+	mainModel.table.SetColumns([]table.ColumnSpec[config.Row]{ColumnFromConfig(msg)})
+	mainModel.SetFilters(nil)
+
+	pattern, err := parseFindTerm("took")
+	if err != nil {
+		t.Fatal(err)
+	}
+	mainModel.setFindPattern(pattern)
+	if len(mainModel.findMatches) != 2 || mainModel.findStatus() != "match 1/2" {
+		t.Errorf("Expected to be at match 1/2, got %s", mainModel.findStatus())
+	}
+	mainModel.findNext(mainModel.table.Cursor(), true)
+	if mainModel.table.Cursor() != 2 || mainModel.findStatus() != "match 2/2" {
+		t.Errorf("Expected to be at match 2/2, got %s", mainModel.findStatus())
+	}
+	mainModel.findNext(mainModel.table.Cursor(), true)
+	if mainModel.table.Cursor() != 0 {
+		t.Errorf("Expected to wrap around to row 0, got %d", mainModel.table.Cursor())
+	}
+	mainModel.findNext(mainModel.table.Cursor(), false)
+	if mainModel.table.Cursor() != 2 {
+		t.Errorf("Expected to wrap around to row 2, got %d", mainModel.table.Cursor())
+	}
+
+	// Upper case letters make the term case sensitive, and filtering keeps the matches up to date
+	pattern, _ = parseFindTerm("Took")
+	mainModel.setFindPattern(pattern)
+	if len(mainModel.findMatches) != 1 {
+		t.Errorf("Expected 1 match, got %d", len(mainModel.findMatches))
+	}
+	mainModel.SetFilters([]config.Filter{{Term: "hello"}})
+	if mainModel.findStatus() != "no matches" {
+		t.Errorf("Expected no matches, got %s", mainModel.findStatus())
+	}
+
+	pattern, _ = parseFindTerm(`/\d{3}ms/`)
+	mainModel.SetFilters(nil)
+	mainModel.setFindPattern(pattern)
+	if len(mainModel.findMatches) != 1 || mainModel.findMatches[0] != 2 {
+		t.Errorf("Expected row 2 to match, got %v", mainModel.findMatches)
+	}
+	if _, err := parseFindTerm("/(/"); err == nil {
+		t.Errorf("Expected an invalid regex to fail")
+	}
+}
+
+func TestContext(t *testing.T) {
+	testRows := make([]string, 10)
+	for i := range testRows {
+		testRows[i] = fmt.Sprintf(`{"msg":"row %d"}`, i)
+	}
+	testRows[2] = `{"msg":"error 2"}`
+	testRows[3] = `{"msg":"error 3"}`
+	testRows[8] = `{"msg":"error 8"}`
+	mainModel := model{rows: rowsFrom(testRows...), view: config.LogView{Context: 1}}
+
+	mainModel.SetFilters([]config.Filter{{Term: "error"}})
+	expected := []string{"row 1", "error 2", "error 3", "row 4", "⋯", "row 7", "error 8", "row 9"}
+	if shown := shownRows(mainModel.filteredRows); shown != strings.Join(expected, ", ") {
+		t.Errorf("Expected %v, got %s", expected, shown)
+	}
+	if !mainModel.filteredRows[0].Context || mainModel.filteredRows[1].Context {
+		t.Errorf("Expected only the rows around the matches to be context rows")
+	}
+
+	// Rows that arrive later get context too, and may close the gap to the rows before them
+	mainModel.appendRows(rowsFrom(`{"msg":"error 10"}`, `{"msg":"row 11"}`, `{"msg":"row 12"}`))
+	expected = append(expected, "error 10", "row 11")
+	if shown := shownRows(mainModel.filteredRows); shown != strings.Join(expected, ", ") {
+		t.Errorf("Expected %v, got %s", expected, shown)
+	}
+}
+
+// shownRows lists the messages of rows, or the text of marker rows.
+func shownRows(rows []config.Row) string {
+	shown := make([]string, len(rows))
+	for i, row := range rows {
+		shown[i] = row.Line
+		if !row.Marker {
+			shown[i] = gjson.Get(row.Line, "msg").String()
+		}
+	}
+	return strings.Join(shown, ", ")
+}
+
+func TestQuickFilter(t *testing.T) {
+	configHome := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", configHome)
+	if err := os.Mkdir(filepath.Join(configHome, "gloglog"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	config.TheConfig = &config.Config{SavedViews: []*config.LogView{{Name: "test"}}}
+
+	testRows := []string{
+		`{"level":"info","trace_id":"a"}`,
+		`{"level":"error","trace_id":"b"}`,
+		`{"level":"info","trace_id":"b"}`,
+	}
+	level := config.Attribute{Name: "Level", Selectors: []string{"json(level)"}}
+	trace := config.Attribute{Name: "Trace ID", Selectors: []string{"json(trace_id)"}}
+	view := config.LogView{Attrs: []config.Attribute{level, trace}}
+	mainModel := model{rows: rowsFrom(testRows...), view: view, search: search.FromLogView(view, 40, 15)}
+	columns := []table.ColumnSpec[config.Row]{ColumnFromConfig(level), ColumnFromConfig(trace)}
+	mainModel.table.SetColumns(columns)
+	mainModel.SetFilters(nil)
+
+	mainModel.table.MoveDown(2)
+	mainModel.quickFilter(config.Equal)
+	if len(mainModel.filteredRows) != 2 || mainModel.table.Cursor() != 1 {
+		t.Errorf("Expected 2 rows with the cursor on row 1, got %d and %d",
+			len(mainModel.filteredRows), mainModel.table.Cursor())
+	}
+	if query := config.FormatQuery(config.TheConfig.SavedViews[0].Filters); query != `Level == "info"` {
+		t.Errorf("Expected the filter to be saved, got %s", query)
+	}
+
+	mainModel.filterByTrace()
+	if len(mainModel.filteredRows) != 2 || mainModel.status != `Filtered by `+"`Trace ID`"+` == "b"` {
+		t.Errorf("Expected 2 rows, got %d (%s)", len(mainModel.filteredRows), mainModel.status)
+	}
+
+	mainModel.table.MoveRight(1)
+	mainModel.quickFilter(config.NotEqual)
+	if len(mainModel.filteredRows) != 0 || len(mainModel.view.Filters) != 2 {
+		t.Errorf("Expected no rows and 2 filters, got %d and %d",
+			len(mainModel.filteredRows), len(mainModel.view.Filters))
+	}
+}
+
+func TestUpdatedSchema(t *testing.T) {
+	configHome := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", configHome)
+	if err := os.Mkdir(filepath.Join(configHome, "gloglog"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	config.TheConfig = &config.Config{SavedViews: []*config.LogView{{Name: "test"}}}
+
+	level := config.Attribute{Name: "Level", Selectors: []string{"json(level)"}}
+	view := config.LogView{
+		Attrs:   []config.Attribute{level},
+		Filters: []config.Filter{{Term: "error", Operator: config.Equal, Attr: &level}},
+	}
+	testRows := rowsFrom(`{"level":"info","severity":"error"}`, `{"level":"error","severity":"info"}`)
+	mainModel := model{
+		state:  stateSchema,
+		rows:   testRows,
+		view:   view,
+		schema: schema.FromLogView(view, 40, 15),
+		search: search.FromLogView(view, 40, 15),
+	}
+	mainModel.SetFilters(view.Filters)
+	if len(mainModel.filteredRows) != 1 || mainModel.filteredRows[0] != testRows[1] {
+		t.Fatalf("Expected the second row, got %v", mainModel.filteredRows)
+	}
+
+	// Filters follow the attribute's new selectors, and new attributes can be queried
+	attrs := []config.Attribute{
+		{Name: "Level", Selectors: []string{"json(severity)"}},
+		{Name: "Raw level", Selectors: []string{"json(level)"}},
+	}
+	updated, _ := mainModel.Update(schema.UpdatedSchemaMsg{Attributes: attrs})
+	mainModel = updated.(model)
+	if len(mainModel.filteredRows) != 1 || mainModel.filteredRows[0] != testRows[0] {
+		t.Errorf("Expected the first row, got %v", mainModel.filteredRows)
+	}
+	if _, err := config.ParseQuery("`Raw level` == \"info\"", mainModel.view); err != nil {
+		t.Errorf("Expected the new attribute to be known, got %s", err)
+	}
+}
+
+func TestAppendRows(t *testing.T) {
+	mainModel := model{rows: rowsFrom(`{"msg":"hello"}`)}
+	mainModel.SetFilters([]config.Filter{{Term: "hello"}})
+
+	mainModel.appendRows(rowsFrom(`{"msg":"goodbye"}`, `{"msg":"hello again"}`))
+	if len(mainModel.rows) != 3 {
+		t.Errorf("Expected 3 rows, got %d", len(mainModel.rows))
+	}
+	if len(mainModel.filteredRows) != 2 {
+		t.Errorf("Expected 2 filtered rows, got %d", len(mainModel.filteredRows))
+	}
+	if mainModel.table.Cursor() != 1 {
+		t.Errorf("Expected cursor to follow the last row, got %d", mainModel.table.Cursor())
+	}
+}
+
+func TestZoomWithoutRows(t *testing.T) {
+	mainModel := model{}
+	updated, _ := mainModel.Update(tea.KeyMsg{Type: tea.KeySpace, Runes: []rune{' '}})
+	mainModel = updated.(model)
+	if mainModel.state != stateTable {
+		t.Errorf("Expected to stay in the table without rows to zoom in on, got state %d", mainModel.state)
+	}
+	mainModel.View()
+}
+
+func TestIngestFailure(t *testing.T) {
+	var mainModel tea.Model = model{ingestAddress: "no such address"}
+	mainModel, cmd := mainModel.Update(startIngestingMsg{})
+	for i := 0; cmd != nil && i < 5; i++ {
+		mainModel, cmd = mainModel.Update(cmd())
+	}
+	if status := mainModel.(model).statusLine(); strings.Contains(status, "ingesting") {
+		t.Errorf("Expected the status line to stop saying that rows are ingested, got %s", status)
+	}
+	if mainModel.(model).status == "" {
+		t.Errorf("Expected the status line to show why ingesting failed")
+	}
+}
+
+func TestMergeByTime(t *testing.T) {
+	rows := []config.Row{
+		{Line: `{"t":"2020-01-01T00:00:01Z","msg":"a1"}`, Origin: "a.log"},
+		{Line: `not json, belongs to a1`, Origin: "a.log"},
+		{Line: `{"t":"2020-01-01T00:00:03Z","msg":"a3"}`, Origin: "a.log"},
+		{Line: `{"t":"2020-01-01T00:00:00Z","msg":"b0"}`, Origin: "b.log"},
+		{Line: `{"t":"2020-01-01T00:00:02Z","msg":"b2"}`, Origin: "b.log"},
+	}
+	attr := &config.Attribute{Name: "Time", Selectors: []string{"json(t)"}, Type: "time"}
+	merged := mergeByTime(rows, attr)
+
+	expected := []string{"b0", "a1", "", "b2", "a3"}
+	for i, row := range merged {
+		msg := valueGetterFromSelectors([]string{"json(msg)"}, "", nil)(row.Line)
+		if msg != expected[i] {
+			t.Errorf("Expected row %d to be '%s', got '%s'", i, expected[i], msg)
+		}
+	}
+
+	// Rows whose times can't be parsed stay in the order they were read
+	rows = make([]config.Row, 8)
+	for i := range rows {
+		line := fmt.Sprintf(`{"t":%d,"msg":"%d"}`, 1577836800000+i, i)
+		rows[i] = config.Row{Line: line, Origin: "ab"[i%2 : i%2+1]}
+	}
+	merged = mergeByTime(rows, attr)
+	if shown := shownRows(merged); shown != "0, 1, 2, 3, 4, 5, 6, 7" {
+		t.Errorf("Expected the rows in order, got %s", shown)
+	}
+}
+
+func rowsFrom(lines ...string) []config.Row {
+	rows := make([]config.Row, len(lines))
+	for i, line := range lines {
+		rows[i] = config.Row{Line: line}
+	}
+	return rows
+}
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/torarvid/gloglog/config"
+)
+
+// grokPatterns are the built-in named patterns that pattern(name, field) selector steps can use.
+// Like in grok, a pattern refers to another one with %{NAME}, or with %{NAME:field} to capture what
+// it matches as field.
+//
+// The upper case ones are building blocks, while the lower case ones parse whole lines of common
+// access log formats.
+var grokPatterns = map[string]string{
+	"INT":               `[+-]?\d+`,
+	"NUMBER":            `[+-]?(?:\d+(?:\.\d*)?|\.\d+)`,
+	"WORD":              `\w+`,
+	"NOTSPACE":          `\S+`,
+	"SPACE":             `\s*`,
+	"DATA":              `.*?`,
+	"GREEDYDATA":        `.*`,
+	"QUOTEDSTRING":      `"(?:[^"\\]|\\.)*"`,
+	"IPV4":              `\d{1,3}(?:\.\d{1,3}){3}`,
+	"IPV6":              `[0-9A-Fa-f]*:[0-9A-Fa-f:.]+`,
+	"IP":                `(?:%{IPV4}|%{IPV6})`,
+	"HOSTNAME":          `[0-9A-Za-z][0-9A-Za-z.-]*`,
+	"IPORHOST":          `(?:%{IP}|%{HOSTNAME})`,
+	"DATE":              `\d{4}-\d{2}-\d{2}`,
+	"TIME":              `\d{2}:\d{2}:\d{2}(?:\.\d+)?`,
+	"TIMESTAMP_ISO8601": `%{DATE}[T ]%{TIME}(?:Z|[+-]\d{2}:?\d{2})?`,
+	"HTTPDATE":          `\d{2}/\w{3}/\d{4}:\d{2}:\d{2}:\d{2} [+-]\d{4}`,
+	"LOGLEVEL":          `(?i:trace|debug|info|notice|warn(?:ing)?|error|err|crit(?:ical)?|fatal|panic|alert|emerg)`,
+
+	"apache_common": `%{IPORHOST:client} %{NOTSPACE:ident} %{NOTSPACE:auth} \[%{HTTPDATE:time}\] ` +
+		`"(?:%{WORD:method} %{NOTSPACE:path}(?: HTTP/%{NUMBER:http_version})?|%{DATA:request})" ` +
+		`%{INT:status} (?:%{INT:bytes}|-)`,
+	"apache_combined": `%{apache_common} "%{DATA:referrer}" "%{DATA:user_agent}"`,
+	"nginx_common":    `%{apache_common}`,
+	"nginx_combined":  `%{apache_combined}`,
+	"aws_alb": `%{NOTSPACE:type} %{TIMESTAMP_ISO8601:time} %{NOTSPACE:elb} ` +
+		`%{IPORHOST:client_ip}:%{INT:client_port} (?:%{IPORHOST:target_ip}:%{INT:target_port}|-) ` +
+		`%{NUMBER:request_processing_time} %{NUMBER:target_processing_time} ` +
+		`%{NUMBER:response_processing_time} %{INT:elb_status_code} (?:%{INT:target_status_code}|-) ` +
+		`%{INT:received_bytes} %{INT:sent_bytes} ` +
+		`"%{NOTSPACE:method} %{NOTSPACE:url} %{NOTSPACE:http_version}" "%{DATA:user_agent}" ` +
+		`%{NOTSPACE:ssl_cipher} %{NOTSPACE:ssl_protocol} %{NOTSPACE:target_group_arn} ` +
+		`"%{DATA:trace_id}"%{GREEDYDATA:rest}`,
+	"aws_elb": `%{TIMESTAMP_ISO8601:time} %{NOTSPACE:elb} ` +
+		`%{IPORHOST:client_ip}:%{INT:client_port} (?:%{IPORHOST:backend_ip}:%{INT:backend_port}|-) ` +
+		`%{NUMBER:request_processing_time} %{NUMBER:backend_processing_time} ` +
+		`%{NUMBER:response_processing_time} %{INT:elb_status_code} (?:%{INT:backend_status_code}|-) ` +
+		`%{INT:received_bytes} %{INT:sent_bytes} ` +
+		`"%{NOTSPACE:method} %{NOTSPACE:url} %{NOTSPACE:http_version}" "%{DATA:user_agent}"` +
+		`%{GREEDYDATA:rest}`,
+	"cloudfront": `%{DATE:date}\t%{TIME:time}\t%{NOTSPACE:edge_location}\t%{INT:bytes}\t` +
+		`%{IP:client_ip}\t%{WORD:method}\t%{NOTSPACE:host}\t%{NOTSPACE:path}\t%{INT:status}\t` +
+		`%{NOTSPACE:referrer}\t%{NOTSPACE:user_agent}\t%{NOTSPACE:query}\t%{NOTSPACE:cookie}\t` +
+		`%{NOTSPACE:result_type}\t%{NOTSPACE:request_id}\t%{NOTSPACE:host_header}\t` +
+		`%{NOTSPACE:protocol}\t%{INT:request_bytes}\t%{NUMBER:time_taken}%{GREEDYDATA:rest}`,
+}
+
+var grokReference = regexp.MustCompile(`%\{(\w+)(?::(\w+))?\}`)
+
+// lookupPattern returns the named pattern, preferring those defined in the config file.
+func lookupPattern(name string) (string, bool) {
+	if config.TheConfig != nil {
+		if pattern, ok := config.TheConfig.Patterns[name]; ok {
+			return pattern, true
+		}
+	}
+	pattern, ok := grokPatterns[name]
+	return pattern, ok
+}
+
+// compilePattern compiles the named pattern into a regular expression where each captured field is
+// a named group.
+func compilePattern(name string) (*regexp.Regexp, error) {
+	pattern, ok := lookupPattern(name)
+	if !ok {
+		return nil, fmt.Errorf("no pattern named %s", name)
+	}
+	expanded, err := expandPattern(pattern, 0)
+	if err != nil {
+		return nil, err
+	}
+	return regexp.Compile("^" + expanded)
+}
+
+// maxPatternDepth is how deeply patterns can refer to each other, which stops patterns that refer
+// to themselves.
+const maxPatternDepth = 20
+
+func expandPattern(pattern string, depth int) (string, error) {
+	if depth > maxPatternDepth {
+		return "", fmt.Errorf("patterns nested too deeply in %s", pattern)
+	}
+	var err error
+	expanded := grokReference.ReplaceAllStringFunc(pattern, func(reference string) string {
+		if err != nil {
+			return ""
+		}
+		parts := grokReference.FindStringSubmatch(reference)
+		referenced, ok := lookupPattern(parts[1])
+		if !ok {
+			err = fmt.Errorf("no pattern named %s", parts[1])
+			return ""
+		}
+		var inner string
+		inner, err = expandPattern(referenced, depth+1)
+		if parts[2] != "" {
+			return "(?P<" + parts[2] + ">" + inner + ")"
+		}
+		return "(?:" + inner + ")"
+	})
+	return expanded, err
+}
+
+// patternStep returns a step for args, "name, field", that matches the named pattern and yields
+// what it captured as field, or "" if the pattern doesn't match.
+func patternStep(args string) (selectorStep, error) {
+	name, field, found := strings.Cut(args, ",")
+	name, field = strings.TrimSpace(name), strings.TrimSpace(field)
+	if !found || field == "" {
+		return nil, fmt.Errorf("pattern needs both a pattern name and a field")
+	}
+	re, err := compilePattern(name)
+	if err != nil {
+		return nil, err
+	}
+	index := re.SubexpIndex(field)
+	if index < 0 {
+		return nil, fmt.Errorf("pattern %s has no field %s", name, field)
+	}
+	return func(s string) string {
+		match := re.FindStringSubmatch(s)
+		if match == nil {
+			return ""
+		}
+		return match[index]
+	}, nil
+}
@@ -23,6 +23,7 @@ var (
 				HelpStyle.PaddingLeft(4).
 				PaddingBottom(1).
 				PaddingRight(4)
+	errorStyle  = lipgloss.NewStyle().Foreground(lipgloss.Color("9"))
 	detailStyle = lipgloss.NewStyle().
 			BorderLeft(true).
 			BorderStyle(lipgloss.NormalBorder()).
@@ -73,20 +74,52 @@ type Filter struct {
 	Operator config.FilterOp
 	Attr     *config.Attribute
 	inputs   []textinput.Model
+	// err tells what is wrong with what was entered in the inputs, if anything.
+	err string
+	// node is the filter as it was loaded, which keeps what the inputs can't edit, like groups.
+	node config.Filter
 }
 
 func (f Filter) FilterValue() string { return "" }
 
 func (a Filter) View() string {
 	labels := []string{"Term", "Operator", "Attr"}
-	parts := make([]string, len(labels))
+	parts := make([]string, len(labels), len(labels)+1)
 	for i, label := range labels {
 		parts[i] = label + "\n" + a.inputs[i].View()
 	}
+	if a.err != "" {
+		parts = append(parts, errorStyle.Render(a.err))
+	}
 
 	return strings.Join(parts, "\n\n")
 }
 
+// apply sets the filter to what was entered in its inputs, unless that doesn't make a valid filter.
+// An empty operator means contains, and an empty attribute means the whole row.
+func (f *Filter) apply(lv config.LogView) error {
+	op := config.Contains
+	if value := strings.TrimSpace(f.inputs[1].Value()); value != "" {
+		var err error
+		if op, err = config.ParseFilterOp(value); err != nil {
+			return err
+		}
+	}
+	var attr *config.Attribute
+	if name := strings.TrimSpace(f.inputs[2].Value()); name != "" {
+		var err error
+		if attr, err = lv.GetAttributeWithName(name); err != nil {
+			return err
+		}
+	}
+	filter := config.Filter{Term: f.inputs[0].Value(), Operator: op, Attr: attr}
+	if _, err := filter.Matcher(); err != nil {
+		return err
+	}
+	f.Term, f.Operator, f.Attr, f.err = filter.Term, filter.Operator, filter.Attr, ""
+	return nil
+}
+
 type Model struct {
 	Filters  []Filter
 	list     list.Model
@@ -138,6 +171,7 @@ func FromLogView(lv config.LogView, width, height int) Model {
 	}
 	for i, filter := range lv.Filters {
 		filters[i] = newFilter(filter.Term, filter.Operator, filter.Attr, attrPlaceholder)
+		filters[i].node = filter
 	}
 	items := listItemsFromFilters(filters)
 	keyMap := DefaultKeyMap()
@@ -159,6 +193,14 @@ func FromLogView(lv config.LogView, width, height int) Model {
 	return Model{Filters: filters, list: l, keyMap: keyMap, logView: lv}
 }
 
+// SetFilters replaces the filters being edited, like when they were entered as a query.
+func (m *Model) SetFilters(filters []config.Filter) {
+	m.logView.Filters = filters
+	m.Filters = FromLogView(m.logView, 0, 0).Filters
+	m.deselect()
+	m.list.SetItems(listItemsFromFilters(m.Filters))
+}
+
 func listItemsFromFilters(filters []Filter) []list.Item {
 	items := make([]list.Item, len(filters))
 	for i, filter := range filters {
@@ -195,17 +237,16 @@ func (m Model) Update(msg tea.Msg) (Model, tea.Cmd) {
 			m.deselect()
 		case key.Matches(msg, m.keyMap.EditFilter):
 			if m.selected == nil {
-				m.selectFilter(m.list.Index())
+				// Groups of filters can only be edited as a query
+				if i := m.list.Index(); i < len(m.Filters) && !m.Filters[i].node.IsGroup() {
+					m.selectFilter(i)
+				}
 				return m, nil
 			} else {
 				i := *m.selected
-				m.Filters[i].Term = m.Filters[i].inputs[0].Value()
-				// TODO: handle errors here and show it to the user
-				if op, err := config.ParseFilterOp(m.Filters[i].inputs[1].Value()); err == nil {
-					m.Filters[i].Operator = op
-				}
-				if attr, err := m.logView.GetAttributeWithName(m.Filters[i].inputs[2].Value()); err == nil {
-					m.Filters[i].Attr = attr
+				if err := m.Filters[i].apply(m.logView); err != nil {
+					m.Filters[i].err = err.Error()
+					return m, nil
 				}
 				m.deselect()
 				m.list.SetItems(listItemsFromFilters(m.Filters))
@@ -258,6 +299,11 @@ func (m *Model) focusPrevInput() {
 	}
 }
 
+// SetAttrs updates the attributes that filters can be applied to.
+func (m *Model) SetAttrs(attrs []config.Attribute) {
+	m.logView.Attrs = attrs
+}
+
 type UpdatedFiltersMsg struct {
 	Filters []config.Filter
 }
@@ -266,10 +312,11 @@ func (m Model) UpdateFilters() tea.Cmd {
 	return func() tea.Msg {
 		cfgFilters := make([]config.Filter, len(m.Filters))
 		for i, filter := range m.Filters {
-			cfgFilters[i] = config.Filter{
-				Term:     filter.Term,
-				Operator: filter.Operator,
-				Attr:     filter.Attr,
+			cfgFilters[i] = filter.node
+			if !filter.node.IsGroup() {
+				cfgFilters[i].Term = filter.Term
+				cfgFilters[i].Operator = filter.Operator
+				cfgFilters[i].Attr = filter.Attr
 			}
 		}
 		return UpdatedFiltersMsg{Filters: cfgFilters}
@@ -321,7 +368,13 @@ func (d itemDelegate) Render(w io.Writer, m list.Model, index int, listItem list
 	if filter.Attr != nil {
 		name = filter.Attr.Name
 	}
-	str := fmt.Sprintf("%d. %s", index+1, name)
+	str := fmt.Sprintf("%d. %s %s %q", index+1, name, filter.Operator, filter.Term)
+	if filter.node.Not {
+		str = fmt.Sprintf("%d. not %s %s %q", index+1, name, filter.Operator, filter.Term)
+	}
+	if filter.node.IsGroup() {
+		str = fmt.Sprintf("%d. %s", index+1, config.FormatQuery([]config.Filter{filter.node}))
+	}
 
 	fn := itemStyle.Render
 	if index == m.Index() {
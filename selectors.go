@@ -0,0 +1,189 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/tidwall/gjson"
+)
+
+// selectorStep is one step of a selector pipe. It turns the output of the previous step into the
+// input of the next one.
+type selectorStep func(string) string
+
+// parseSelector splits a selector like "json(msg) | regex((?P<ms>\d+)ms, ms)" into its steps.
+// Steps that aren't recognized pass their input on unchanged.
+func parseSelector(selector string) ([]selectorStep, error) {
+	parts := splitPipe(selector)
+	steps := make([]selectorStep, 0, len(parts))
+	for _, part := range parts {
+		step, err := parseSelectorStep(strings.TrimSpace(part))
+		if err != nil {
+			return nil, fmt.Errorf("invalid selector step '%s': %w", part, err)
+		}
+		if step != nil {
+			steps = append(steps, step)
+		}
+	}
+	return steps, nil
+}
+
+func parseSelectorStep(step string) (selectorStep, error) {
+	name, args, found := strings.Cut(step, "(")
+	if !found || !strings.HasSuffix(args, ")") {
+		return nil, nil
+	}
+	args = args[:len(args)-1]
+	switch name {
+	case "json":
+		if args == "." {
+			return nil, nil
+		}
+		return func(s string) string {
+			return gjson.Get(s, args).String()
+		}, nil
+	case "regex":
+		return regexStep(args)
+	case "pattern":
+		return patternStep(args)
+	case "logfmt":
+		key := strings.TrimSpace(args)
+		return func(s string) string {
+			return logfmtValue(s, key)
+		}, nil
+	default:
+		return nil, nil
+	}
+}
+
+// regexStep returns a step that matches the pattern in args, "pattern, group", and yields the named
+// or numbered group, or "" if the pattern doesn't match. Without a group, it yields the first group
+// if the pattern has any, and otherwise the whole match. The pattern is compiled once, here.
+func regexStep(args string) (selectorStep, error) {
+	pattern, group := args, ""
+	if i := strings.LastIndex(args, ","); i >= 0 {
+		if candidate := strings.TrimSpace(args[i+1:]); isGroupName(candidate) {
+			pattern, group = strings.TrimSpace(args[:i]), candidate
+		}
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, err
+	}
+	index := 0
+	if re.NumSubexp() > 0 {
+		index = 1
+	}
+	if group != "" {
+		if n, err := strconv.Atoi(group); err == nil {
+			index = n
+		} else {
+			index = re.SubexpIndex(group)
+		}
+		if index < 0 || index > re.NumSubexp() {
+			return nil, fmt.Errorf("no group %s in %s", group, pattern)
+		}
+	}
+	return func(s string) string {
+		match := re.FindStringSubmatch(s)
+		if match == nil {
+			return ""
+		}
+		return match[index]
+	}, nil
+}
+
+// logfmtValue returns the value of key in a logfmt line like `level=info msg="hello \"world\""`,
+// or "" if the line doesn't have it. Quoted values may contain escapes like \" and \n. A key without
+// a value, as in `debug level=info`, has the value "true".
+func logfmtValue(line, key string) string {
+	i := 0
+	for i < len(line) {
+		for i < len(line) && line[i] == ' ' {
+			i++
+		}
+		start := i
+		for i < len(line) && line[i] != '=' && line[i] != ' ' {
+			i++
+		}
+		name := line[start:i]
+		if i == len(line) || line[i] == ' ' {
+			if name == key && name != "" {
+				return "true"
+			}
+			continue
+		}
+		i++ // '='
+		var value string
+		if i < len(line) && line[i] == '"' {
+			var b strings.Builder
+			for i++; i < len(line) && line[i] != '"'; i++ {
+				if line[i] == '\\' && i+1 < len(line) {
+					i++
+					switch line[i] {
+					case 'n':
+						b.WriteByte('\n')
+					case 't':
+						b.WriteByte('\t')
+					case 'r':
+						b.WriteByte('\r')
+					default:
+						b.WriteByte(line[i])
+					}
+					continue
+				}
+				b.WriteByte(line[i])
+			}
+			i++ // closing '"'
+			value = b.String()
+		} else {
+			start = i
+			for i < len(line) && line[i] != ' ' {
+				i++
+			}
+			value = line[start:i]
+		}
+		if name == key {
+			return value
+		}
+	}
+	return ""
+}
+
+// isGroupName reports whether s can name a group of a regular expression, by name or by number.
+func isGroupName(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, r := range s {
+		if r != '_' && !('a' <= r && r <= 'z') && !('A' <= r && r <= 'Z') && !('0' <= r && r <= '9') {
+			return false
+		}
+	}
+	return true
+}
+
+// splitPipe splits selector on the | characters that separate its steps, leaving those within
+// parentheses or escaped by a backslash, as in regular expressions, alone.
+func splitPipe(selector string) []string {
+	parts := make([]string, 0, 1)
+	depth, start := 0, 0
+	for i := 0; i < len(selector); i++ {
+		switch selector[i] {
+		case '\\':
+			i++
+		case '(':
+			depth++
+		case ')':
+			depth--
+		case '|':
+			if depth == 0 {
+				parts = append(parts, selector[start:i])
+				start = i + 1
+			}
+		}
+	}
+	return append(parts, selector[start:])
+}
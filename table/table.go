@@ -1,6 +1,8 @@
 package table
 
 import (
+	"strings"
+
 	"github.com/charmbracelet/bubbles/key"
 	"github.com/charmbracelet/bubbles/viewport"
 	tea "github.com/charmbracelet/bubbletea"
@@ -19,6 +21,10 @@ type Model[E any] struct {
 	hcursor int
 	focus   bool
 	styles  Styles
+	banner  func(E) (string, bool)
+	dimmed  func(E) bool
+	// highlight finds the parts of a cell's text that should stand out, if anything should.
+	highlight func(string) [][]int
 
 	viewport viewport.Model
 }
@@ -110,6 +116,9 @@ type Styles struct {
 	Header   lipgloss.Style
 	Cell     lipgloss.Style
 	Selected lipgloss.Style
+	Banner   lipgloss.Style
+	Match    lipgloss.Style
+	Dimmed   lipgloss.Style
 }
 
 // DefaultStyles returns a set of default style definitions for this table.
@@ -118,6 +127,9 @@ func DefaultStyles() Styles {
 		Selected: lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("212")),
 		Header:   lipgloss.NewStyle().Bold(true).Padding(0, 1),
 		Cell:     lipgloss.NewStyle().Padding(0, 1),
+		Banner:   lipgloss.NewStyle().Padding(0, 1).Faint(true),
+		Match:    lipgloss.NewStyle().Reverse(true),
+		Dimmed:   lipgloss.NewStyle().Faint(true),
 	}
 }
 
@@ -193,6 +205,22 @@ func WithStyles[E any](s Styles) Option[E] {
 	}
 }
 
+// WithBanners sets a function that picks out rows that should be rendered as a single line of text
+// spanning all columns, rather than as cells.
+func WithBanners[E any](banner func(E) (string, bool)) Option[E] {
+	return func(m *Model[E]) {
+		m.banner = banner
+	}
+}
+
+// WithDimmed sets a function that picks out rows that should be rendered with the Dimmed style,
+// unless they are selected.
+func WithDimmed[E any](dimmed func(E) bool) Option[E] {
+	return func(m *Model[E]) {
+		m.dimmed = dimmed
+	}
+}
+
 // WithKeyMap sets the key map.
 func WithKeyMap[E any](km KeyMap) Option[E] {
 	return func(m *Model[E]) {
@@ -295,9 +323,24 @@ func (m *Model[E]) SetColumns(cols []ColumnSpec[E]) {
 	m.UpdateViewport()
 }
 
-// SetRows set a new rows state.
+// SetHighlight sets a function that finds the parts of a cell's text that are rendered with the
+// Match style, as pairs of byte offsets like regexp.FindAllStringIndex returns. A nil function
+// highlights nothing.
+func (m *Model[E]) SetHighlight(highlight func(string) [][]int) {
+	m.highlight = highlight
+	m.UpdateViewport()
+}
+
+// Columns returns the table columns.
+func (m Model[E]) Columns() []ColumnSpec[E] {
+	return m.cols
+}
+
+// SetRows set a new rows state. The cursor stays where it was, unless there are now fewer rows.
 func (m *Model[E]) SetRows(r []E) {
 	m.rows = r
+	m.cursor = clamp(m.cursor, 0, max(len(r)-1, 0))
+	m.yOffset = clamp(m.yOffset, 0, m.cursor)
 	m.UpdateViewport()
 }
 
@@ -328,6 +371,11 @@ func (m Model[E]) Cursor() int {
 	return m.cursor
 }
 
+// ColumnCursor returns the index of the selected column, which is the first one shown.
+func (m Model[E]) ColumnCursor() int {
+	return m.hcursor
+}
+
 // SetCursor sets the cursor position in the table.
 func (m *Model[E]) SetCursor(n int) {
 	m.cursor = clamp(n, 0, len(m.rows)-1)
@@ -414,9 +462,21 @@ func (m Model[E]) headersView() string {
 }
 
 func (m *Model[E]) renderRow(rowID int) string {
+	if m.banner != nil {
+		if text, ok := m.banner(m.rows[rowID]); ok {
+			return m.renderBanner(rowID, text)
+		}
+	}
+
 	s := make([]string, 0, len(m.cols))
 	remainingWidth := m.Width()
 	padding := m.styles.Cell.GetHorizontalPadding()
+	selected := rowID == m.cursor
+	cellStyle := m.styles.Cell
+	if selected && m.highlight != nil {
+		// Highlights end all styling, so the selected row's style must be kept up cell by cell
+		cellStyle = cellStyle.Copy().Inherit(m.styles.Selected)
+	}
 	for i, col := range m.cols {
 		if i < m.hcursor {
 			continue
@@ -433,13 +493,64 @@ func (m *Model[E]) renderRow(rowID int) string {
 			MaxWidth(colWidth).
 			Inline(true)
 		value := col.GetValue(row)
-		content := style.Render(runewidth.Truncate(value, colWidth, "…"))
-		renderedCell := m.styles.Cell.Render(content)
+		if firstLine, _, multiline := strings.Cut(value, "\n"); multiline {
+			// Only a row's first line fits in the table
+			value = firstLine + " …"
+		}
+		text := m.highlighted(runewidth.Truncate(value, colWidth, "…"), colWidth, selected)
+		content := style.Render(text)
+		renderedCell := cellStyle.Render(content)
 		s = append(s, renderedCell)
 	}
 
 	row := lipgloss.JoinHorizontal(lipgloss.Left, s...)
 
+	if selected {
+		return m.styles.Selected.Render(row)
+	}
+	if m.dimmed != nil && m.dimmed(m.rows[rowID]) {
+		return m.styles.Dimmed.Render(row)
+	}
+
+	return row
+}
+
+// highlighted renders the parts of text that the highlight function finds with the Match style.
+// The rest of the text, padded to width, is rendered with the Selected style if selected is set.
+func (m *Model[E]) highlighted(text string, width int, selected bool) string {
+	if m.highlight == nil {
+		return text
+	}
+	base := lipgloss.NewStyle()
+	if selected {
+		base = base.Inherit(m.styles.Selected)
+	}
+	matches := m.highlight(text)
+	if len(matches) == 0 && !selected {
+		return text
+	}
+	text = runewidth.FillRight(text, width)
+	var b strings.Builder
+	end := 0
+	for _, match := range matches {
+		if match[0] < end || match[1] <= match[0] {
+			continue
+		}
+		if match[0] > end {
+			b.WriteString(base.Render(text[end:match[0]]))
+		}
+		b.WriteString(m.styles.Match.Copy().Inherit(base).Render(text[match[0]:match[1]]))
+		end = match[1]
+	}
+	b.WriteString(base.Render(text[end:]))
+	return b.String()
+}
+
+func (m *Model[E]) renderBanner(rowID int, text string) string {
+	width := max(m.Width()-m.styles.Banner.GetHorizontalPadding(), 1)
+	style := lipgloss.NewStyle().Width(width).MaxWidth(width).Inline(true)
+	row := m.styles.Banner.Render(style.Render(runewidth.Truncate(text, width, "…")))
+
 	if rowID == m.cursor {
 		return m.styles.Selected.Render(row)
 	}
@@ -0,0 +1,190 @@
+package timerange
+
+import (
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/bubbles/help"
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/torarvid/gloglog/config"
+)
+
+var (
+	titleStyle = lipgloss.NewStyle().MarginLeft(2).MarginTop(1).MarginBottom(1)
+	formStyle  = lipgloss.NewStyle().PaddingLeft(4).PaddingRight(4)
+	helpStyle  = lipgloss.NewStyle().PaddingLeft(4).PaddingTop(1).PaddingBottom(1)
+	errorStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("9"))
+)
+
+type KeyMap struct {
+	SelectNextField key.Binding
+	SelectPrevField key.Binding
+	Apply           key.Binding
+	Clear           key.Binding
+	Exit            key.Binding
+}
+
+func DefaultKeyMap() KeyMap {
+	return KeyMap{
+		SelectNextField: key.NewBinding(
+			key.WithKeys("tab"),
+			key.WithHelp("tab", "next field"),
+		),
+		SelectPrevField: key.NewBinding(
+			key.WithKeys("shift+tab"),
+			key.WithHelp("shift+tab", "previous field"),
+		),
+		Apply: key.NewBinding(
+			key.WithKeys("enter"),
+			key.WithHelp("enter", "apply"),
+		),
+		Clear: key.NewBinding(
+			key.WithKeys("ctrl+d"),
+			key.WithHelp("ctrl+d", "clear range"),
+		),
+		Exit: key.NewBinding(
+			key.WithKeys("esc"),
+			key.WithHelp("esc", "exit"),
+		),
+	}
+}
+
+// Model is a dialog where the time range of a view can be edited: which time attribute it applies
+// to, and where it starts and ends.
+type Model struct {
+	inputs  []textinput.Model
+	keyMap  KeyMap
+	help    help.Model
+	logView config.LogView
+	// err tells what is wrong with what was entered in the inputs, if anything.
+	err string
+}
+
+func FromLogView(lv config.LogView) Model {
+	labels := []string{"<name of time attribute>", "-15m, today 09:00 or 2024-01-02T14:02:00Z", "now"}
+	inputs := make([]textinput.Model, len(labels))
+	for i, placeholder := range labels {
+		inputs[i] = textinput.New()
+		inputs[i].Placeholder = placeholder
+		inputs[i].CharLimit = 100
+	}
+	if attr, err := (config.TimeRange{}).Attribute(lv); err == nil {
+		inputs[0].Placeholder = attr.Name
+	}
+	m := Model{inputs: inputs, keyMap: DefaultKeyMap(), help: help.New(), logView: lv}
+	m.SetRange(lv.TimeRange)
+	return m
+}
+
+// SetRange replaces the range being edited, and focuses the first of its ends.
+func (m *Model) SetRange(tr *config.TimeRange) {
+	values := []string{"", "", ""}
+	if tr != nil {
+		values = []string{tr.Attr, tr.From, tr.To}
+	}
+	for i, value := range values {
+		m.inputs[i].SetValue(value)
+		m.inputs[i].Blur()
+	}
+	m.inputs[1].Focus()
+	m.err = ""
+}
+
+// SetLogView updates the view whose attributes the range can apply to.
+func (m *Model) SetLogView(lv config.LogView) {
+	m.logView = lv
+}
+
+func (m Model) Init() tea.Cmd {
+	return nil
+}
+
+type Close struct{}
+
+// UpdatedRangeMsg is sent when a range has been entered, or cleared, in which case Range is nil.
+type UpdatedRangeMsg struct {
+	Range *config.TimeRange
+}
+
+func (m Model) Update(msg tea.Msg) (Model, tea.Cmd) {
+	if msg, ok := msg.(tea.KeyMsg); ok {
+		switch {
+		case key.Matches(msg, m.keyMap.SelectNextField):
+			m.focus(1)
+			return m, nil
+		case key.Matches(msg, m.keyMap.SelectPrevField):
+			m.focus(-1)
+			return m, nil
+		case key.Matches(msg, m.keyMap.Exit):
+			return m, func() tea.Msg { return Close{} }
+		case key.Matches(msg, m.keyMap.Clear):
+			m.SetRange(nil)
+			return m, func() tea.Msg { return UpdatedRangeMsg{} }
+		case key.Matches(msg, m.keyMap.Apply):
+			tr, err := m.timeRange()
+			if err != nil {
+				m.err = err.Error()
+				return m, nil
+			}
+			m.err = ""
+			return m, func() tea.Msg { return UpdatedRangeMsg{Range: tr} }
+		}
+	}
+	var cmd tea.Cmd
+	for i, input := range m.inputs {
+		if input.Focused() {
+			m.inputs[i], cmd = input.Update(msg)
+			break
+		}
+	}
+	return m, cmd
+}
+
+// timeRange returns the range that was entered in the inputs, or nil if both ends are empty.
+func (m Model) timeRange() (*config.TimeRange, error) {
+	tr := config.TimeRange{
+		Attr: strings.TrimSpace(m.inputs[0].Value()),
+		From: strings.TrimSpace(m.inputs[1].Value()),
+		To:   strings.TrimSpace(m.inputs[2].Value()),
+	}
+	if tr.From == "" && tr.To == "" {
+		return nil, nil
+	}
+	if _, err := tr.Attribute(m.logView); err != nil {
+		return nil, err
+	}
+	if _, err := tr.Matcher(time.Now()); err != nil {
+		return nil, err
+	}
+	return &tr, nil
+}
+
+func (m *Model) focus(step int) {
+	for i := range m.inputs {
+		if m.inputs[i].Focused() {
+			m.inputs[i].Blur()
+			m.inputs[(i+step+len(m.inputs))%len(m.inputs)].Focus()
+			return
+		}
+	}
+}
+
+func (m Model) View() string {
+	labels := []string{"Attr", "From", "To"}
+	parts := make([]string, len(labels), len(labels)+1)
+	for i, label := range labels {
+		parts[i] = label + "\n" + m.inputs[i].View()
+	}
+	if m.err != "" {
+		parts = append(parts, errorStyle.Render(m.err))
+	}
+	bindings := []key.Binding{
+		m.keyMap.SelectNextField, m.keyMap.Apply, m.keyMap.Clear, m.keyMap.Exit,
+	}
+	return titleStyle.Render("Time range") + "\n" +
+		formStyle.Render(strings.Join(parts, "\n\n")) + "\n" +
+		helpStyle.Render(m.help.ShortHelpView(bindings))
+}
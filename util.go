@@ -8,3 +8,11 @@ func contains[E comparable](haystack []E, needle E) bool {
 	}
 	return false
 }
+
+// orDefault returns s, or def if s is empty.
+func orDefault(s, def string) string {
+	if s == "" {
+		return def
+	}
+	return s
+}